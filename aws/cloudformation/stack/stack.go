@@ -47,6 +47,7 @@ func (r *Stack) Assert(t *testing.T, stacks ...*cloudformation.Stack) *Stack {
 		t.Fatal("more than one matching stack was found")
 	default:
 		r.stack = stacks[0]
+		shared.Touch("aws_cloudformation_stack", aws.StringValue(r.stack.StackName))
 	}
 
 	r.filters = []shared.Filter{}
@@ -67,6 +68,7 @@ func (r *Stack) First(t *testing.T, stacks ...*cloudformation.Stack) *Stack {
 		t.Fatal("no matching stack was found")
 	} else {
 		r.stack = stacks[0]
+		shared.Touch("aws_cloudformation_stack", aws.StringValue(r.stack.StackName))
 	}
 
 	r.filters = []shared.Filter{}
@@ -47,6 +47,7 @@ func (r *Trail) Assert(t *testing.T, trails ...*cloudtrail.Trail) *Trail {
 		t.Fatal("more than one matching trail was found")
 	default:
 		r.trail = trails[0]
+		shared.Touch("aws_cloudtrail", aws.StringValue(r.trail.Name))
 	}
 
 	r.filters = []shared.Filter{}
@@ -67,6 +68,7 @@ func (r *Trail) First(t *testing.T, trails ...*cloudtrail.Trail) *Trail {
 		t.Fatal("no matching trail was found")
 	} else {
 		r.trail = trails[0]
+		shared.Touch("aws_cloudtrail", aws.StringValue(r.trail.Name))
 	}
 
 	r.filters = []shared.Filter{}
@@ -47,6 +47,7 @@ func (a *Alarm) Assert(t *testing.T, alarms ...*cloudwatch.MetricAlarm) *Alarm {
 		t.Fatal("more than one matching alarm was found")
 	default:
 		a.alarm = alarms[0]
+		shared.Touch("aws_cloudwatch_metric_alarm", aws.StringValue(a.alarm.AlarmName))
 	}
 
 	a.filters = []shared.Filter{}
@@ -67,6 +68,7 @@ func (a *Alarm) First(t *testing.T, alarms ...*cloudwatch.MetricAlarm) *Alarm {
 		t.Fatal("no matching metric was found")
 	} else {
 		a.alarm = alarms[0]
+		shared.Touch("aws_cloudwatch_metric_alarm", aws.StringValue(a.alarm.AlarmName))
 	}
 
 	a.filters = []shared.Filter{}
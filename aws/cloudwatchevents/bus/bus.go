@@ -53,6 +53,7 @@ func (r *Bus) Assert(t *testing.T, buses ...*cloudwatchevents.EventBus) *Bus {
 		t.Fatal("more than one matching bus was found")
 	default:
 		r.bus = buses[0]
+		shared.Touch("aws_cloudwatch_event_bus", aws.StringValue(r.bus.Name))
 	}
 
 	r.filters = []shared.Filter{}
@@ -73,6 +74,7 @@ func (r *Bus) First(t *testing.T, buses ...*cloudwatchevents.EventBus) *Bus {
 		t.Fatal("no matching bus was found")
 	} else {
 		r.bus = buses[0]
+		shared.Touch("aws_cloudwatch_event_bus", aws.StringValue(r.bus.Name))
 	}
 
 	r.filters = []shared.Filter{}
@@ -52,6 +52,7 @@ func (r *Rule) Assert(t *testing.T, rules ...*cloudwatchevents.Rule) *Rule {
 		t.Fatal("more than one matching rule was found")
 	default:
 		r.rule = rules[0]
+		shared.Touch("aws_cloudwatch_event_rule", aws.StringValue(r.rule.Name))
 	}
 
 	r.filters = []shared.Filter{}
@@ -72,6 +73,7 @@ func (r *Rule) First(t *testing.T, rules ...*cloudwatchevents.Rule) *Rule {
 		t.Fatal("no matching rule was found")
 	} else {
 		r.rule = rules[0]
+		shared.Touch("aws_cloudwatch_event_rule", aws.StringValue(r.rule.Name))
 	}
 
 	r.filters = []shared.Filter{}
@@ -46,6 +46,7 @@ func (g *Target) Assert(t *testing.T, targets ...*cloudwatchevents.Target) *Targ
 		t.Fatal("more than one matching target was found")
 	default:
 		g.target = targets[0]
+		shared.Touch("aws_cloudwatch_event_target", aws.StringValue(g.target.Id))
 	}
 
 	g.filters = []shared.Filter{}
@@ -66,6 +67,7 @@ func (g *Target) First(t *testing.T, targets ...*cloudwatchevents.Target) *Targe
 		t.Fatal("no matching rule was found")
 	} else {
 		g.target = targets[0]
+		shared.Touch("aws_cloudwatch_event_target", aws.StringValue(g.target.Id))
 	}
 
 	g.filters = []shared.Filter{}
@@ -47,6 +47,7 @@ func (r *Group) Assert(t *testing.T, groups ...*cloudwatchlogs.LogGroup) *Group
 		t.Fatal("more than one matching group was found")
 	default:
 		r.group = groups[0]
+		shared.Touch("aws_cloudwatch_log_group", aws.StringValue(r.group.LogGroupName))
 	}
 
 	r.filters = []shared.Filter{}
@@ -67,6 +68,7 @@ func (r *Group) First(t *testing.T, groups ...*cloudwatchlogs.LogGroup) *Group {
 		t.Fatal("no matching group was found")
 	} else {
 		r.group = groups[0]
+		shared.Touch("aws_cloudwatch_log_group", aws.StringValue(r.group.LogGroupName))
 	}
 
 	r.filters = []shared.Filter{}
@@ -44,6 +44,7 @@ func (m *MetricFilter) Assert(t *testing.T, filters ...*cloudwatchlogs.MetricFil
 		t.Error("more than one matching filter was found")
 	default:
 		m.selected = filters[0]
+		shared.Touch("aws_cloudwatch_log_metric_filter", aws.StringValue(m.selected.FilterName))
 	}
 
 	m.filterList = []shared.Filter{}
@@ -64,6 +65,7 @@ func (m *MetricFilter) First(t *testing.T, filters ...*cloudwatchlogs.MetricFilt
 		t.Error("no matching filter was found")
 	} else {
 		m.selected = filters[0]
+		shared.Touch("aws_cloudwatch_log_metric_filter", aws.StringValue(m.selected.FilterName))
 	}
 
 	m.filterList = []shared.Filter{}
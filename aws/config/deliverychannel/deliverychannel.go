@@ -44,6 +44,7 @@ func (d *DeliveryChannel) Assert(t *testing.T, channels ...*configservice.Delive
 		t.Error("more than one matching channel was found")
 	default:
 		d.channel = channels[0]
+		shared.Touch("aws_config_delivery_channel", aws.StringValue(d.channel.Name))
 	}
 
 	d.filters = []shared.Filter{}
@@ -64,6 +65,7 @@ func (d *DeliveryChannel) First(t *testing.T, channels ...*configservice.Deliver
 		t.Error("no matching channel was found")
 	} else {
 		d.channel = channels[0]
+		shared.Touch("aws_config_delivery_channel", aws.StringValue(d.channel.Name))
 	}
 
 	d.filters = []shared.Filter{}
@@ -77,6 +77,7 @@ func (r *Recorder) Assert(t *testing.T, recorders ...*configservice.Configuratio
 		t.Error("more than one matching recorder was found")
 	default:
 		r.recorder = recorders[0]
+		shared.Touch("aws_config_configuration_recorder", aws.StringValue(r.recorder.Name))
 	}
 
 	r.filters = []shared.Filter{}
@@ -97,6 +98,7 @@ func (r *Recorder) First(t *testing.T, recorders ...*configservice.Configuration
 		t.Error("no matching recorder was found")
 	} else {
 		r.recorder = recorders[0]
+		shared.Touch("aws_config_configuration_recorder", aws.StringValue(r.recorder.Name))
 	}
 
 	r.filters = []shared.Filter{}
@@ -44,6 +44,7 @@ func (r *Rule) Assert(t *testing.T, rules ...*configservice.ConfigRule) *Rule {
 		t.Error("more than one matching rule was found")
 	default:
 		r.rule = rules[0]
+		shared.Touch("aws_config_config_rule", aws.StringValue(r.rule.ConfigRuleName))
 	}
 
 	r.filters = []shared.Filter{}
@@ -64,6 +65,7 @@ func (r *Rule) First(t *testing.T, rules ...*configservice.ConfigRule) *Rule {
 		t.Error("no matching rule was found")
 	} else {
 		r.rule = rules[0]
+		shared.Touch("aws_config_config_rule", aws.StringValue(r.rule.ConfigRuleName))
 	}
 
 	r.filters = []shared.Filter{}
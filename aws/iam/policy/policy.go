@@ -58,6 +58,7 @@ func (p *Policy) Assert(t *testing.T, policies ...*iam.Policy) *Policy {
 		t.Fatal("more than one matching policy was found")
 	default:
 		p.policy = policies[0]
+		shared.Touch("aws_iam_policy", aws.StringValue(p.policy.Arn))
 	}
 
 	p.filters = []shared.Filter{}
@@ -78,6 +79,7 @@ func (p *Policy) First(t *testing.T, policies ...*iam.Policy) *Policy {
 		t.Fatal("no matching policy was found")
 	} else {
 		p.policy = policies[0]
+		shared.Touch("aws_iam_policy", aws.StringValue(p.policy.Arn))
 	}
 
 	p.filters = []shared.Filter{}
@@ -59,6 +59,7 @@ func (a *Attached) Assert(t *testing.T, policies ...*iam.AttachedPolicy) *Attach
 		t.Fatal("more than one matching attached policy was found")
 	default:
 		a.attached = policies[0]
+		shared.Touch("aws_iam_role_policy_attachment", a.roleName, aws.StringValue(a.attached.PolicyArn))
 	}
 
 	a.filters = []shared.Filter{}
@@ -79,6 +80,7 @@ func (a *Attached) First(t *testing.T, policies ...*iam.AttachedPolicy) *Attache
 		t.Fatal("no matching attached policy was found")
 	} else {
 		a.attached = policies[0]
+		shared.Touch("aws_iam_role_policy_attachment", a.roleName, aws.StringValue(a.attached.PolicyArn))
 	}
 
 	a.filters = []shared.Filter{}
@@ -81,6 +81,7 @@ func (r *Role) Assert(t *testing.T, roles ...*iam.Role) *Role {
 		t.Fatal("more than one matching role was found")
 	default:
 		r.role = roles[0]
+		shared.Touch("aws_iam_role", aws.StringValue(r.role.RoleName))
 	}
 
 	r.filters = []shared.Filter{}
@@ -101,6 +102,7 @@ func (r *Role) First(t *testing.T, roles ...*iam.Role) *Role {
 		t.Fatal("no matching role was found")
 	} else {
 		r.role = roles[0]
+		shared.Touch("aws_iam_role", aws.StringValue(r.role.RoleName))
 	}
 
 	r.filters = []shared.Filter{}
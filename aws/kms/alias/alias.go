@@ -77,6 +77,7 @@ func (a *Alias) Assert(t *testing.T, aliases ...*kms.AliasListEntry) *Alias {
 		t.Fatal("more than one matching alias was found")
 	default:
 		a.alias = aliases[0]
+		shared.Touch("aws_kms_alias", aws.StringValue(a.alias.AliasName))
 	}
 
 	a.filters = []shared.Filter{}
@@ -97,6 +98,7 @@ func (a *Alias) First(t *testing.T, aliases ...*kms.AliasListEntry) *Alias {
 		t.Fatal("no matching alias was found")
 	} else {
 		a.alias = aliases[0]
+		shared.Touch("aws_kms_alias", aws.StringValue(a.alias.AliasName))
 	}
 
 	a.filters = []shared.Filter{}
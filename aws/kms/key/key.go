@@ -78,6 +78,7 @@ func (a *Key) Assert(t *testing.T, keys ...*kms.KeyMetadata) *Key {
 		t.Fatal("more than one matching key was found")
 	default:
 		a.key = keys[0]
+		shared.Touch("aws_kms_key", aws.StringValue(a.key.KeyId))
 	}
 
 	a.filters = []shared.Filter{}
@@ -98,6 +99,7 @@ func (a *Key) First(t *testing.T, keys ...*kms.KeyMetadata) *Key {
 		t.Fatal("no matching key was found")
 	} else {
 		a.key = keys[0]
+		shared.Touch("aws_kms_key", aws.StringValue(a.key.KeyId))
 	}
 
 	a.filters = []shared.Filter{}
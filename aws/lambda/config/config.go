@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/GSA/grace-tftest/aws/shared"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/service/lambda"
@@ -158,5 +159,6 @@ func (c *Config) getConfig() (*lambda.FunctionConfiguration, error) {
 	if err != nil {
 		return nil, err
 	}
+	shared.Touch("aws_lambda_function", c.functionName)
 	return cfg, nil
 }
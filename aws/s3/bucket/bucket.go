@@ -12,6 +12,7 @@ import (
 	"github.com/GSA/grace-tftest/aws/s3/bucket/notification"
 	"github.com/GSA/grace-tftest/aws/s3/bucket/policy"
 	"github.com/GSA/grace-tftest/aws/s3/bucket/pubaccblk"
+	"github.com/GSA/grace-tftest/aws/shared"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/service/s3"
 )
@@ -71,6 +72,7 @@ func (b *Bucket) Assert(t *testing.T) *Bucket {
 		t.Fatal(err)
 		return b
 	}
+	shared.Touch("aws_s3_bucket", b.name)
 	return b
 }
 
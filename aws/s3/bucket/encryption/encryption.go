@@ -49,6 +49,7 @@ func (e *Encryption) Assert(t *testing.T, rules ...*s3.ServerSideEncryptionRule)
 		t.Fatal("more than one matching lifecycle rule was found")
 	default:
 		e.rule = rules[0]
+		shared.Touch("aws_s3_bucket_server_side_encryption_configuration", e.name)
 	}
 
 	e.filters = []shared.Filter{}
@@ -69,6 +70,7 @@ func (e *Encryption) First(t *testing.T, rules ...*s3.ServerSideEncryptionRule)
 		t.Fatal("no matching lifecycle rule was found")
 	} else {
 		e.rule = rules[0]
+		shared.Touch("aws_s3_bucket_server_side_encryption_configuration", e.name)
 	}
 
 	e.filters = []shared.Filter{}
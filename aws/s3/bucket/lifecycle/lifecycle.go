@@ -51,6 +51,7 @@ func (l *Lifecycle) Assert(t *testing.T, rules ...*s3.LifecycleRule) *Lifecycle
 		t.Fatal("more than one matching lifecycle rule was found")
 	default:
 		l.rule = rules[0]
+		shared.Touch("aws_s3_bucket_lifecycle_configuration", l.name)
 	}
 
 	l.filters = []shared.Filter{}
@@ -71,6 +72,7 @@ func (l *Lifecycle) First(t *testing.T, rules ...*s3.LifecycleRule) *Lifecycle {
 		t.Fatal("no matching lifecycle rule was found")
 	} else {
 		l.rule = rules[0]
+		shared.Touch("aws_s3_bucket_lifecycle_configuration", l.name)
 	}
 
 	l.filters = []shared.Filter{}
@@ -49,6 +49,7 @@ func (n *Notification) Assert(t *testing.T, configs ...*Configuration) *Notifica
 		t.Fatal("more than one matching configuration was found")
 	default:
 		n.config = configs[0]
+		shared.Touch("aws_s3_bucket_notification", n.name)
 	}
 
 	n.filters = []shared.Filter{}
@@ -69,6 +70,7 @@ func (n *Notification) First(t *testing.T, configs ...*Configuration) *Notificat
 		t.Fatal("no matching configuration was found")
 	} else {
 		n.config = configs[0]
+		shared.Touch("aws_s3_bucket_notification", n.name)
 	}
 
 	n.filters = []shared.Filter{}
@@ -6,6 +6,7 @@ package policy
 import (
 	"testing"
 
+	"github.com/GSA/grace-tftest/aws/shared"
 	"github.com/GSA/grace-tftest/aws/shared/policy"
 	"github.com/GSA/grace-tftest/aws/shared/policy/statement"
 	"github.com/aws/aws-sdk-go/aws"
@@ -35,6 +36,7 @@ func (p *Policy) Statement(t *testing.T, doc *policy.Document) *statement.Statem
 			t.Errorf("failed to query statements: %v", err)
 			return nil
 		}
+		shared.Touch("aws_s3_bucket_policy", p.name)
 	}
 	return statement.New(doc)
 }
@@ -50,6 +50,7 @@ func (e *PublicAccessBlock) Assert(t *testing.T, configs ...*s3.PublicAccessBloc
 		t.Fatal("more than one matching public access block configuration was found")
 	default:
 		e.config = configs[0]
+		shared.Touch("aws_s3_bucket_public_access_block", e.name)
 	}
 
 	e.filters = []shared.Filter{}
@@ -70,6 +71,7 @@ func (e *PublicAccessBlock) First(t *testing.T, configs ...*s3.PublicAccessBlock
 		t.Fatal("no matching public access block configuration was found")
 	} else {
 		e.config = configs[0]
+		shared.Touch("aws_s3_bucket_public_access_block", e.name)
 	}
 
 	e.filters = []shared.Filter{}
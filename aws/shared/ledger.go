@@ -0,0 +1,87 @@
+package shared
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// LedgerEnv is the environment variable containing the path of the
+// ledger that the resources selected by Assert and First are recorded
+// into, resources are not recorded when it is not set
+const LedgerEnv = "TFTEST_LEDGER_FILE"
+
+// LedgerEntry is a resource that was selected by an assertion, Kind is
+// the type of the Terraform resource, for example: aws_kms_key, and
+// IDs are the values of its attributes that identify it, such as its
+// name or ARN
+type LedgerEntry struct {
+	Kind string
+	IDs  []string
+}
+
+func (e LedgerEntry) String() string {
+	return strings.Join(append([]string{e.Kind}, e.IDs...), "\t")
+}
+
+var ledger = make(map[string]bool)
+var muLedger sync.Mutex
+
+// Touch records the resource of kind identified by ids into the ledger
+// at LedgerEnv, each resource is only recorded once per process
+func Touch(kind string, ids ...string) {
+	path := os.Getenv(LedgerEnv)
+	if len(path) == 0 || len(ids) == 0 {
+		return
+	}
+	line := LedgerEntry{Kind: kind, IDs: ids}.String()
+
+	muLedger.Lock()
+	defer muLedger.Unlock()
+	if ledger[line] {
+		return
+	}
+
+	// the ledger is appended to on every call so the
+	// entries survive a test that calls t.Fatal
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		fmt.Printf("failed to open ledger: %q -> %v\n", path, err)
+		return
+	}
+	_, err = fmt.Fprintln(f, line)
+	cerr := f.Close()
+	if err != nil {
+		fmt.Printf("failed to write ledger: %q -> %v\n", path, err)
+		return
+	}
+	if cerr != nil {
+		fmt.Printf("failed to close ledger: %q -> %v\n", path, cerr)
+		return
+	}
+	ledger[line] = true
+}
+
+// ParseLedger parses the entries written by Touch
+func ParseLedger(r io.Reader) ([]LedgerEntry, error) {
+	var entries []LedgerEntry
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("failed to parse ledger line: %q", line)
+		}
+		entries = append(entries, LedgerEntry{Kind: fields[0], IDs: fields[1:]})
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %v", err)
+	}
+	return entries, nil
+}
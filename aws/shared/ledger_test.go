@@ -0,0 +1,82 @@
+package shared
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTouch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledger")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ledger.txt")
+	err = os.Setenv(LedgerEnv, path)
+	if err != nil {
+		t.Fatalf("failed to set %s: %v", LedgerEnv, err)
+	}
+	defer os.Unsetenv(LedgerEnv)
+
+	Touch("aws_kms_key", "1234")
+	Touch("aws_iam_role_policy_attachment", "role", "arn:aws:iam::aws:policy/a")
+	Touch("aws_kms_key", "1234")
+	Touch("aws_kms_alias")
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open ledger: %v", err)
+	}
+	defer f.Close()
+	actual, err := ParseLedger(f)
+	if err != nil {
+		t.Fatalf("failed to parse ledger: %v", err)
+	}
+
+	expected := []LedgerEntry{
+		{Kind: "aws_kms_key", IDs: []string{"1234"}},
+		{Kind: "aws_iam_role_policy_attachment", IDs: []string{"role", "arn:aws:iam::aws:policy/a"}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("ledger invalid, expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestParseLedger(t *testing.T) {
+	tt := map[string]struct {
+		data     string
+		expected []LedgerEntry
+		err      bool
+	}{
+		"empty": {},
+		"entries": {
+			data: "aws_s3_bucket\tlogs\n\naws_kms_key\t1234\n",
+			expected: []LedgerEntry{
+				{Kind: "aws_s3_bucket", IDs: []string{"logs"}},
+				{Kind: "aws_kms_key", IDs: []string{"1234"}},
+			},
+		},
+		"missing ids": {
+			data: "aws_s3_bucket\n",
+			err:  true,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual, err := ParseLedger(strings.NewReader(tc.data))
+			if tc.err != (err != nil) {
+				t.Fatalf("error invalid, expected error: %t, got: %v", tc.err, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("entries invalid, expected: %v, got: %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -68,6 +68,7 @@ func (r *Topic) Assert(t *testing.T, topics ...*Attributes) *Topic {
 		t.Fatal("more than one matching topic was found")
 	default:
 		r.topic = topics[0]
+		shared.Touch("aws_sns_topic", r.topic.TopicArn)
 	}
 
 	r.filters = []shared.Filter{}
@@ -88,6 +89,7 @@ func (r *Topic) First(t *testing.T, topics ...*Attributes) *Topic {
 		t.Fatal("no matching topic was found")
 	} else {
 		r.topic = topics[0]
+		shared.Touch("aws_sns_topic", r.topic.TopicArn)
 	}
 
 	r.filters = []shared.Filter{}
@@ -0,0 +1,379 @@
+package tester
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CassetteMode is how the AWS API requests made by go test are
+// recorded into and replayed from the cassette file of each job
+type CassetteMode string
+
+// the cassette modes
+const (
+	// CassetteOff sends every request to the emulator
+	CassetteOff CassetteMode = ""
+
+	// CassetteRecord saves every request and response between go test
+	// and the emulator along with the Terraform outputs of the job
+	CassetteRecord CassetteMode = "record"
+
+	// CassetteReplay runs go test against the recorded responses
+	// without starting an emulator or running terraform
+	CassetteReplay CassetteMode = "replay"
+)
+
+// cassetteMiss is the status returned for a request that is not in
+// the cassette, it fails the job once the test completes
+const cassetteMiss = http.StatusNotImplemented
+
+// cassette is the JSON encoded content of a cassette file
+type cassette struct {
+	Outputs      map[string]Output `json:"outputs,omitempty"`
+	Interactions []interaction     `json:"interactions"`
+}
+
+// interaction is a single request and its response
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string `json:"method"`
+	URI    string `json:"uri"`
+	Target string `json:"target,omitempty"`
+	body
+}
+
+type recordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	body
+}
+
+// body is kept as text so cassettes are readable and
+// only base64 encoded when it is not valid UTF-8
+type body struct {
+	Body       string `json:"body,omitempty"`
+	BodyBase64 string `json:"body_base64,omitempty"`
+}
+
+func newBody(data []byte) body {
+	if utf8.Valid(data) {
+		return body{Body: string(data)}
+	}
+	return body{BodyBase64: base64.StdEncoding.EncodeToString(data)}
+}
+
+func (b body) bytes() ([]byte, error) {
+	if len(b.BodyBase64) > 0 {
+		return base64.StdEncoding.DecodeString(b.BodyBase64)
+	}
+	return []byte(b.Body), nil
+}
+
+// cassettePath returns the cassette file of the job inside of CassetteDir
+func cassettePath(cfg *Config, name string) string {
+	return filepath.Join(cfg.CassetteDir, unsafeChars.ReplaceAllString(name, "_")+".json")
+}
+
+// readCassette reads the cassette file at path
+func readCassette(path string) (*cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %q -> %v", path, err)
+	}
+	c := &cassette{}
+	err = json.Unmarshal(data, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cassette: %q -> %v", path, err)
+	}
+	return c, nil
+}
+
+// write stores the cassette at path
+func (c *cassette) write(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %q -> %v", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %v", err)
+	}
+	err = ioutil.WriteFile(path, data, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write cassette: %q -> %v", path, err)
+	}
+	return nil
+}
+
+// proxy sits between go test and the emulator, when target is set the
+// requests are forwarded to it and recorded, otherwise they are served
+// from the recorded interactions
+type proxy struct {
+	target string
+	srv    *http.Server
+	port   int
+
+	mu       sync.Mutex
+	cassette *cassette
+	used     []bool
+	misses   []string
+}
+
+// startProxy listens on an available port of the loopback interface
+func startProxy(target string, c *cassette) (*proxy, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the cassette proxy: %v", err)
+	}
+
+	p := &proxy{
+		target:   strings.TrimRight(target, "/"),
+		port:     l.Addr().(*net.TCPAddr).Port,
+		cassette: c,
+		used:     make([]bool, len(c.Interactions)),
+	}
+	p.srv = &http.Server{Handler: p}
+	go func() {
+		// Serve always returns an error once closed
+		_ = p.srv.Serve(l)
+	}()
+	return p, nil
+}
+
+// Endpoint returns the URL of the proxy
+func (p *proxy) Endpoint() string {
+	return fmt.Sprintf(urlFmt, p.port)
+}
+
+// close stops the proxy and returns an error listing the
+// requests that were not found in the cassette
+func (p *proxy) close() error {
+	err := p.srv.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close the cassette proxy: %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.misses) > 0 {
+		return fmt.Errorf("requests not found in cassette: %s", strings.Join(p.misses, ", "))
+	}
+	return nil
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	req := recordedRequest{
+		Method: r.Method,
+		URI:    r.URL.RequestURI(),
+		Target: r.Header.Get("X-Amz-Target"),
+		body:   newBody(data),
+	}
+
+	var resp recordedResponse
+	if len(p.target) > 0 {
+		resp, err = p.record(r, req, data)
+	} else {
+		resp, err = p.replay(req)
+	}
+	if err != nil {
+		status := cassetteMiss
+		if len(p.target) > 0 {
+			status = http.StatusBadGateway
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	respData, err := resp.bytes()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode response body: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for k, v := range resp.Header {
+		if k == "Content-Length" {
+			continue
+		}
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(respData)))
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(respData)
+}
+
+// record forwards the request to the emulator and saves its response
+func (p *proxy) record(r *http.Request, req recordedRequest, data []byte) (recordedResponse, error) {
+	out, err := http.NewRequest(r.Method, p.target+req.URI, bytes.NewReader(data))
+	if err != nil {
+		return recordedResponse{}, fmt.Errorf("failed to create request: %v", err)
+	}
+	out.Header = r.Header.Clone()
+
+	// the transport is used directly so redirects are recorded
+	in, err := http.DefaultTransport.RoundTrip(out)
+	if err != nil {
+		return recordedResponse{}, fmt.Errorf("failed to forward request: %v", err)
+	}
+	defer in.Body.Close()
+	respData, err := ioutil.ReadAll(in.Body)
+	if err != nil {
+		return recordedResponse{}, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	resp := recordedResponse{Status: in.StatusCode, Header: in.Header, body: newBody(respData)}
+	p.mu.Lock()
+	p.cassette.Interactions = append(p.cassette.Interactions, interaction{Request: req, Response: resp})
+	p.mu.Unlock()
+	return resp, nil
+}
+
+// replay returns the response of the first unused interaction with the
+// same request, the body must match as well since the query protocol
+// services send every action as POST / with the action in the body
+func (p *proxy) replay(req recordedRequest) (recordedResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	match := -1
+	for i, in := range p.cassette.Interactions {
+		if p.used[i] || in.Request.Method != req.Method || in.Request.URI != req.URI || in.Request.Target != req.Target {
+			continue
+		}
+		if in.Request.body == req.body {
+			match = i
+			break
+		}
+	}
+	if match < 0 {
+		miss := req.Method + " " + req.URI
+		if len(req.Target) > 0 {
+			miss += " " + req.Target
+		}
+		p.misses = append(p.misses, miss)
+		return recordedResponse{}, fmt.Errorf("request not found in cassette: %s", miss)
+	}
+
+	p.used[match] = true
+	return p.cassette.Interactions[match].Response, nil
+}
+
+// cassetteMode returns the cassette mode of the job, plan-only
+// jobs never reach the emulator so they are not recorded
+func (j *job) cassetteMode(cfg *Config) CassetteMode {
+	if j.planOnly(cfg) {
+		return CassetteOff
+	}
+	return cfg.Cassettes
+}
+
+// setProxyEnv directs the processes of the job to the proxy and
+// returns the environment to restore once the proxy is closed
+func (j *job) setProxyEnv(p *proxy) ([]string, error) {
+	j.mu.Lock()
+	env := append([]string{}, j.Env...)
+	j.mu.Unlock()
+
+	err := j.setEndpointEnv(p.Endpoint())
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// runRecorded runs go test through a proxy that records every request
+// to the emulator at endpoint into the cassette of the job
+func (j *job) runRecorded(ctx context.Context, cfg *Config, endpoint string) error {
+	c := &cassette{}
+	data, err := ioutil.ReadFile(j.OutputsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read outputs at: %q -> %v", j.OutputsFile, err)
+	}
+	err = json.Unmarshal(data, &c.Outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse outputs at: %q -> %v", j.OutputsFile, err)
+	}
+
+	p, err := startProxy(endpoint, c)
+	if err != nil {
+		return err
+	}
+	env, err := j.setProxyEnv(p)
+	if err != nil {
+		_ = p.close()
+		return err
+	}
+
+	err = j.runTest(ctx)
+	perr := p.close()
+	j.mu.Lock()
+	j.Env = env
+	j.mu.Unlock()
+	if perr != nil {
+		return perr
+	}
+
+	// the cassette is written even when the test fails
+	// so the assertions are able to be fixed by replaying
+	path := cassettePath(cfg, j.Name)
+	werr := c.write(path)
+	if werr != nil {
+		return werr
+	}
+	j.printf("recorded %d requests into cassette: %s\n", len(c.Interactions), path)
+	return err
+}
+
+// runReplay runs go test against the cassette of the job, every request
+// that is not in the cassette fails the job
+func (j *job) runReplay(ctx context.Context, cfg *Config) error {
+	c, err := readCassette(cassettePath(cfg, j.Name))
+	if err != nil {
+		return err
+	}
+	err = j.setOutputs(c.Outputs)
+	if err != nil {
+		return err
+	}
+
+	return j.runPhase(ctx, phaseTest, cfg.Timeouts.Test, func(ctx context.Context) error {
+		p, err := startProxy("", c)
+		if err != nil {
+			return err
+		}
+		_, err = j.setProxyEnv(p)
+		if err != nil {
+			_ = p.close()
+			return err
+		}
+
+		err = j.runTest(ctx)
+		perr := p.close()
+		if err != nil && perr != nil {
+			return fmt.Errorf("%v; %v", err, perr)
+		}
+		if err != nil {
+			return err
+		}
+		return perr
+	})
+}
@@ -0,0 +1,159 @@
+package tester
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBody(t *testing.T) {
+	tt := map[string]struct {
+		data   []byte
+		base64 bool
+	}{
+		"text":   {data: []byte(`{"Buckets": []}`)},
+		"binary": {data: []byte{0xff, 0xfe, 0x00}, base64: true},
+		"empty":  {},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			b := newBody(tc.data)
+			if tc.base64 != (len(b.BodyBase64) > 0) {
+				t.Errorf("encoding invalid, expected base64: %t, got: %+v", tc.base64, b)
+			}
+			actual, err := b.bytes()
+			if err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if string(actual) != string(tc.data) {
+				t.Errorf("body invalid, expected: %v, got: %v", tc.data, actual)
+			}
+		})
+	}
+}
+
+// send makes a request to the proxy and returns the status and body
+func send(t *testing.T, p *proxy, method, uri, body string) (int, string) {
+	req, err := http.NewRequest(method, p.Endpoint()+uri, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("X-Amz-Target", "Test.Action")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func TestProxyRecordReplay(t *testing.T) {
+	emu := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.RequestURI() + " " + r.Header.Get("X-Amz-Target") + " " + string(data)))
+	}))
+	defer emu.Close()
+
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cassettes", "job.json")
+
+	c := &cassette{Outputs: map[string]Output{"bucket": {Value: []byte(`"test"`)}}}
+	p, err := startProxy(emu.URL, c)
+	if err != nil {
+		t.Fatalf("failed to start proxy: %v", err)
+	}
+	send(t, p, http.MethodPost, "/", "token=1")
+	send(t, p, http.MethodGet, "/bucket?list-type=2", "")
+	err = p.close()
+	if err != nil {
+		t.Fatalf("failed to close proxy: %v", err)
+	}
+	err = c.write(path)
+	if err != nil {
+		t.Fatalf("failed to write cassette: %v", err)
+	}
+
+	c, err = readCassette(path)
+	if err != nil {
+		t.Fatalf("failed to read cassette: %v", err)
+	}
+	if len(c.Interactions) != 2 || string(c.Outputs["bucket"].Value) != `"test"` {
+		t.Fatalf("cassette invalid: %+v", c)
+	}
+
+	tt := map[string]struct {
+		method   string
+		uri      string
+		body     string
+		status   int
+		expected string
+	}{
+		"same body": {
+			method:   http.MethodGet,
+			uri:      "/bucket?list-type=2",
+			status:   http.StatusOK,
+			expected: "GET /bucket?list-type=2 Test.Action ",
+		},
+		"different body": {
+			method: http.MethodPost,
+			uri:    "/",
+			body:   "token=2",
+			status: cassetteMiss,
+		},
+		"missing": {
+			method: http.MethodDelete,
+			uri:    "/bucket",
+			status: cassetteMiss,
+		},
+	}
+
+	p, err = startProxy("", c)
+	if err != nil {
+		t.Fatalf("failed to start proxy: %v", err)
+	}
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			status, body := send(t, p, tc.method, tc.uri, tc.body)
+			if status != tc.status {
+				t.Errorf("status invalid, expected: %d, got: %d", tc.status, status)
+			}
+			if len(tc.expected) > 0 && body != tc.expected {
+				t.Errorf("body invalid, expected: %q, got: %q", tc.expected, body)
+			}
+		})
+	}
+
+	err = p.close()
+	for _, miss := range []string{"DELETE /bucket Test.Action", "POST / Test.Action"} {
+		if err == nil || !strings.Contains(err.Error(), miss) {
+			t.Errorf("error invalid, expected the missing request: %s, got: %v", miss, err)
+		}
+	}
+}
+
+func TestCassetteMode(t *testing.T) {
+	cfg := &Config{Cassettes: CassetteRecord}
+	if mode := (&job{}).cassetteMode(cfg); mode != CassetteRecord {
+		t.Errorf("mode invalid, expected: %s, got: %s", CassetteRecord, mode)
+	}
+	planOnly := &job{Config: &JobConfig{PlanOnly: true}}
+	if mode := planOnly.cassetteMode(cfg); mode != CassetteOff {
+		t.Errorf("mode invalid, expected off, got: %s", mode)
+	}
+}
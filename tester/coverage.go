@@ -0,0 +1,252 @@
+package tester
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// coverageFile is the name of the coverage profile of each job
+const coverageFile = "coverage.out"
+
+// setCoverage enables the coverage profile of the job, which is
+// written to its artifacts directory
+func (j *job) setCoverage(cfg *Config) {
+	if !cfg.Coverage || len(j.LogDir) == 0 {
+		return
+	}
+	j.CoverProfile = filepath.Join(j.LogDir, coverageFile)
+	j.coverPkg = strings.Join(cfg.CoverPkg, ",")
+}
+
+// testArgs returns the arguments of go test
+func (j *job) testArgs() []string {
+	args := []string{"test", "-v"}
+	if len(j.CoverProfile) > 0 {
+		args = append(args, "-coverprofile="+j.CoverProfile)
+		if len(j.coverPkg) > 0 {
+			args = append(args, "-coverpkg="+j.coverPkg)
+		}
+	}
+	return append(args, j.TestFile)
+}
+
+// coverBlock is a block of statements in a coverage profile
+type coverBlock struct {
+	stmts int
+	count int
+}
+
+// coverProfile is a coverage profile written by go test, the blocks
+// are keyed by their file and position, for example:
+// github.com/GSA/grace-tftest/aws/s3/bucket.go:10.2,12.16
+type coverProfile struct {
+	mode   string
+	blocks map[string]*coverBlock
+}
+
+func newCoverProfile() *coverProfile {
+	return &coverProfile{blocks: make(map[string]*coverBlock)}
+}
+
+// parseCoverProfile parses a profile in the format written by go test
+func parseCoverProfile(r io.Reader) (*coverProfile, error) {
+	p := newCoverProfile()
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if strings.HasPrefix(line, "mode: ") {
+			p.mode = strings.TrimPrefix(line, "mode: ")
+			continue
+		}
+
+		// file:start,end stmts count
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("failed to parse coverage line: %q", line)
+		}
+		stmts, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse coverage line: %q -> %v", line, err)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse coverage line: %q -> %v", line, err)
+		}
+		p.add(fields[0], coverBlock{stmts: stmts, count: count})
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read coverage profile: %v", err)
+	}
+	return p, nil
+}
+
+// add merges the block at pos, a set profile only records whether a
+// block ran while the other modes record the number of times it ran
+func (p *coverProfile) add(pos string, b coverBlock) {
+	existing, ok := p.blocks[pos]
+	if !ok {
+		p.blocks[pos] = &b
+		return
+	}
+	if p.mode == "set" {
+		if b.count > existing.count {
+			existing.count = b.count
+		}
+		return
+	}
+	existing.count += b.count
+}
+
+// merge adds the blocks of other to the profile
+func (p *coverProfile) merge(other *coverProfile) error {
+	if len(p.mode) == 0 {
+		p.mode = other.mode
+	}
+	if other.mode != p.mode {
+		return fmt.Errorf("failed to merge coverage profiles: mode %s does not match %s", other.mode, p.mode)
+	}
+	for pos, b := range other.blocks {
+		p.add(pos, *b)
+	}
+	return nil
+}
+
+// write stores the profile at path in the format written by go test
+func (p *coverProfile) write(path string) error {
+	positions := make([]string, 0, len(p.blocks))
+	for pos := range p.blocks {
+		positions = append(positions, pos)
+	}
+	sort.Strings(positions)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "mode: %s\n", p.mode)
+	for _, pos := range positions {
+		fmt.Fprintf(&b, "%s %d %d\n", pos, p.blocks[pos].stmts, p.blocks[pos].count)
+	}
+
+	err := ioutil.WriteFile(path, b.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write coverage profile: %q -> %v", path, err)
+	}
+	return nil
+}
+
+// coverTotal is the number of statements and covered statements
+type coverTotal struct {
+	stmts   int
+	covered int
+}
+
+func (t coverTotal) String() string {
+	if t.stmts == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(t.covered)/float64(t.stmts))
+}
+
+// coverSummary is the coverage of every package and of all of
+// the packages combined
+type coverSummary struct {
+	Profile  string
+	Total    coverTotal
+	Packages map[string]coverTotal
+}
+
+// summary returns the statement coverage of the profile by package
+func (p *coverProfile) summary() *coverSummary {
+	s := &coverSummary{Packages: make(map[string]coverTotal)}
+	for pos, b := range p.blocks {
+		file := pos
+		if i := strings.LastIndex(pos, ":"); i >= 0 {
+			file = pos[:i]
+		}
+		pkg := path.Dir(file)
+
+		t := s.Packages[pkg]
+		t.stmts += b.stmts
+		s.Total.stmts += b.stmts
+		if b.count > 0 {
+			t.covered += b.stmts
+			s.Total.covered += b.stmts
+		}
+		s.Packages[pkg] = t
+	}
+	return s
+}
+
+// mergeCoverage merges the coverage profile of every job into
+// Config.CoverageFile and returns its summary
+func mergeCoverage(cfg *Config, jobs []*job) (*coverSummary, error) {
+	merged := newCoverProfile()
+	for _, j := range jobs {
+		if len(j.CoverProfile) == 0 {
+			continue
+		}
+		f, err := os.Open(j.CoverProfile)
+		if err != nil {
+			// a job that failed early never wrote a profile
+			if ignoreNotExistsErr(err) == nil {
+				continue
+			}
+			return nil, fmt.Errorf("failed to open coverage profile: %q -> %v", j.CoverProfile, err)
+		}
+		p, err := parseCoverProfile(f)
+		cerr := f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse coverage profile: %q -> %v", j.CoverProfile, err)
+		}
+		if cerr != nil {
+			return nil, fmt.Errorf("failed to close coverage profile: %q -> %v", j.CoverProfile, cerr)
+		}
+		err = merged.merge(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(merged.mode) == 0 {
+		merged.mode = "set"
+	}
+
+	err := merged.write(cfg.CoverageFile)
+	if err != nil {
+		return nil, err
+	}
+	s := merged.summary()
+	s.Profile = cfg.CoverageFile
+	return s, nil
+}
+
+// reportCoverage prints the coverage of all packages combined
+// followed by the coverage of each package
+func reportCoverage(w io.Writer, s *coverSummary) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-20s%-15sof %d statements\n", "COVERAGE", s.Total, s.Total.stmts)
+	fmt.Fprintf(&b, "%-20s%-15s%s\n", "", "PROFILE", s.Profile)
+
+	pkgs := make([]string, 0, len(s.Packages))
+	for pkg := range s.Packages {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		fmt.Fprintf(&b, "%-20s%-15s%s\n", "", s.Packages[pkg], pkg)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		fmt.Printf("failed to write coverage results: %v\n", err)
+	}
+}
@@ -0,0 +1,165 @@
+package tester
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTestArgs(t *testing.T) {
+	tt := map[string]struct {
+		j        *job
+		expected []string
+	}{
+		"no coverage": {
+			j:        &job{TestFile: "bucket_test.go"},
+			expected: []string{"test", "-v", "bucket_test.go"},
+		},
+		"profile": {
+			j:        &job{TestFile: "bucket_test.go", CoverProfile: "/tmp/coverage.out"},
+			expected: []string{"test", "-v", "-coverprofile=/tmp/coverage.out", "bucket_test.go"},
+		},
+		"packages": {
+			j:        &job{TestFile: "bucket_test.go", CoverProfile: "/tmp/coverage.out", coverPkg: "./...,../aws/..."},
+			expected: []string{"test", "-v", "-coverprofile=/tmp/coverage.out", "-coverpkg=./...,../aws/...", "bucket_test.go"},
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual := tc.j.testArgs()
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("args invalid, expected: %v, got: %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMergeCoverProfile(t *testing.T) {
+	tt := map[string]struct {
+		profiles []string
+		expected map[string]coverBlock
+		total    string
+		err      bool
+	}{
+		"set": {
+			profiles: []string{
+				"mode: set\na/x.go:1.1,2.2 2 1\na/x.go:3.1,4.2 3 0\n",
+				"mode: set\na/x.go:1.1,2.2 2 0\na/x.go:3.1,4.2 3 1\nb/y.go:1.1,2.2 5 0\n",
+			},
+			expected: map[string]coverBlock{
+				"a/x.go:1.1,2.2": {stmts: 2, count: 1},
+				"a/x.go:3.1,4.2": {stmts: 3, count: 1},
+				"b/y.go:1.1,2.2": {stmts: 5, count: 0},
+			},
+			total: "50.0%",
+		},
+		"count": {
+			profiles: []string{
+				"mode: count\na/x.go:1.1,2.2 2 3\n",
+				"mode: count\na/x.go:1.1,2.2 2 4\n",
+			},
+			expected: map[string]coverBlock{
+				"a/x.go:1.1,2.2": {stmts: 2, count: 7},
+			},
+			total: "100.0%",
+		},
+		"mismatched modes": {
+			profiles: []string{
+				"mode: set\na/x.go:1.1,2.2 2 1\n",
+				"mode: atomic\na/x.go:1.1,2.2 2 1\n",
+			},
+			err: true,
+		},
+		"invalid line": {
+			profiles: []string{"mode: set\na/x.go:1.1,2.2 2\n"},
+			err:      true,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			merged := newCoverProfile()
+			var err error
+			for _, data := range tc.profiles {
+				var p *coverProfile
+				p, err = parseCoverProfile(strings.NewReader(data))
+				if err != nil {
+					break
+				}
+				err = merged.merge(p)
+				if err != nil {
+					break
+				}
+			}
+			if tc.err != (err != nil) {
+				t.Fatalf("error invalid, expected error: %t, got: %v", tc.err, err)
+			}
+			if tc.err {
+				return
+			}
+
+			actual := make(map[string]coverBlock)
+			for pos, b := range merged.blocks {
+				actual[pos] = *b
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("blocks invalid, expected: %v, got: %v", tc.expected, actual)
+			}
+			if total := merged.summary().Total.String(); total != tc.total {
+				t.Errorf("total invalid, expected: %s, got: %s", tc.total, total)
+			}
+		})
+	}
+}
+
+func TestMergeCoverage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coverage")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	profile := filepath.Join(dir, "job.out")
+	err = ioutil.WriteFile(profile, []byte("mode: set\na/x.go:1.1,2.2 2 1\nb/y.go:1.1,2.2 2 0\n"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	jobs := []*job{
+		{Name: "covered", CoverProfile: profile},
+		{Name: "failed early", CoverProfile: filepath.Join(dir, "missing.out")},
+		{Name: "disabled"},
+	}
+
+	cfg := &Config{CoverageFile: filepath.Join(dir, coverageFile)}
+	s, err := mergeCoverage(cfg, jobs)
+	if err != nil {
+		t.Fatalf("failed to merge coverage: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(cfg.CoverageFile)
+	if err != nil {
+		t.Fatalf("failed to read merged profile: %v", err)
+	}
+	expected := "mode: set\na/x.go:1.1,2.2 2 1\nb/y.go:1.1,2.2 2 0\n"
+	if string(data) != expected {
+		t.Errorf("merged profile invalid, expected: %q, got: %q", expected, data)
+	}
+
+	out := &strings.Builder{}
+	reportCoverage(out, s)
+	for _, line := range []string{
+		"COVERAGE            50.0%          of 4 statements",
+		"                    100.0%         a",
+		"                    0.0%           b",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("report missing line: %q, got: %s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Step is a single command of the infrastructure as code tool
+type Step string
+
+// the steps run by each job
+const (
+	StepInit    Step = "init"
+	StepApply   Step = "apply"
+	StepPlan    Step = "plan"
+	StepDestroy Step = "destroy"
+	StepOutput  Step = "output"
+	StepShow    Step = "show"
+	StepVersion Step = "version"
+)
+
+// Driver runs the infrastructure as code tool that jobs are
+// initialized, applied, planned, and destroyed with
+type Driver interface {
+	// Name returns the name of the tool, for example: terraform
+	Name() string
+
+	// Command returns the executable and the arguments that run the
+	// step, args are additional arguments that must be appended
+	Command(step Step, args ...string) (string, []string)
+
+	// ParseOutputs parses the stdout of StepOutput
+	ParseOutputs(data []byte) (map[string]Output, error)
+
+	// ParseVersion parses the stdout of StepVersion
+	ParseVersion(data []byte) (string, error)
+}
+
+// stepArgs are the arguments shared by the terraform compatible tools
+var stepArgs = map[Step][]string{
+	StepInit:    {"init", "-no-color"},
+	StepApply:   {"apply", "-auto-approve", "-no-color"},
+	StepPlan:    {"plan", "-no-color"},
+	StepDestroy: {"destroy", "-auto-approve", "-no-color"},
+	StepOutput:  {"output", "-json", "-no-color"},
+	StepShow:    {"show", "-json", "-no-color"},
+	StepVersion: {"version", "-json"},
+}
+
+// command returns path, or name if path is not set, with the
+// arguments of the step followed by args
+func command(path, name string, step Step, args []string) (string, []string) {
+	if len(path) == 0 {
+		path = name
+	}
+	return path, append(append([]string{}, stepArgs[step]...), args...)
+}
+
+// parseOutputs parses the JSON encoded outputs of `terraform output -json`
+func parseOutputs(data []byte) (map[string]Output, error) {
+	var outputs map[string]Output
+	err := json.Unmarshal(data, &outputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse outputs: %v", err)
+	}
+	return outputs, nil
+}
+
+// parseVersion parses the JSON encoded output of `terraform version -json`
+func parseVersion(data []byte) (string, error) {
+	var v struct {
+		Version string `json:"terraform_version"`
+	}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse version: %v", err)
+	}
+	return v.Version, nil
+}
+
+// Terraform runs the terraform CLI
+type Terraform struct {
+	// Path is the path to the terraform executable
+	// if it is not set, it will default to terraform
+	Path string
+}
+
+// Name returns terraform
+func (d *Terraform) Name() string {
+	return "terraform"
+}
+
+// Command returns the terraform command for the step
+func (d *Terraform) Command(step Step, args ...string) (string, []string) {
+	return command(d.Path, d.Name(), step, args)
+}
+
+// ParseOutputs parses the output of terraform output -json
+func (d *Terraform) ParseOutputs(data []byte) (map[string]Output, error) {
+	return parseOutputs(data)
+}
+
+// ParseVersion parses the output of terraform version -json
+func (d *Terraform) ParseVersion(data []byte) (string, error) {
+	return parseVersion(data)
+}
+
+// Tofu runs the OpenTofu CLI
+type Tofu struct {
+	// Path is the path to the tofu executable
+	// if it is not set, it will default to tofu
+	Path string
+}
+
+// Name returns tofu
+func (d *Tofu) Name() string {
+	return "tofu"
+}
+
+// Command returns the tofu command for the step
+func (d *Tofu) Command(step Step, args ...string) (string, []string) {
+	return command(d.Path, d.Name(), step, args)
+}
+
+// ParseOutputs parses the output of tofu output -json
+func (d *Tofu) ParseOutputs(data []byte) (map[string]Output, error) {
+	return parseOutputs(data)
+}
+
+// ParseVersion parses the output of tofu version -json, which
+// keeps the terraform_version key for compatibility
+func (d *Tofu) ParseVersion(data []byte) (string, error) {
+	return parseVersion(data)
+}
+
+// Terragrunt runs the Terragrunt CLI, which wraps terraform or tofu
+// using the terragrunt.hcl of the job directory
+type Terragrunt struct {
+	// Path is the path to the terragrunt executable
+	// if it is not set, it will default to terragrunt
+	Path string
+}
+
+// Name returns terragrunt
+func (d *Terragrunt) Name() string {
+	return "terragrunt"
+}
+
+// Command returns the terragrunt command for the step
+func (d *Terragrunt) Command(step Step, args ...string) (string, []string) {
+	path := d.Path
+	if len(path) == 0 {
+		path = d.Name()
+	}
+	// terragrunt reports its own version rather than the wrapped tool's
+	if step == StepVersion {
+		return path, append([]string{"--version"}, args...)
+	}
+	return command(path, d.Name(), step, args)
+}
+
+// ParseOutputs parses the output of terragrunt output -json
+func (d *Terragrunt) ParseOutputs(data []byte) (map[string]Output, error) {
+	return parseOutputs(data)
+}
+
+// ParseVersion parses the output of terragrunt --version,
+// for example: terragrunt version v0.50.0
+func (d *Terragrunt) ParseVersion(data []byte) (string, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("failed to parse version: %q", data)
+	}
+	return strings.TrimPrefix(fields[len(fields)-1], "v"), nil
+}
+
+// defaultDriver is used when Config.Driver is not set
+var defaultDriver Driver = &Terraform{}
+
+// driver returns the driver of the job
+func (j *job) driver() Driver {
+	if j.tool != nil {
+		return j.tool
+	}
+	return defaultDriver
+}
+
+// runStep runs the step inside of dir and waits for it to exit
+func (j *job) runStep(ctx context.Context, dir string, step Step, args ...string) error {
+	d := j.driver()
+	path, argv := d.Command(step, args...)
+	p, err := j.startProcessIn(ctx, dir, path, argv...)
+	if err != nil {
+		return fmt.Errorf("failed to run %s %s: %v", d.Name(), step, err)
+	}
+	err = p.Wait()
+	if err != nil {
+		return fmt.Errorf("failed to wait for %s %s: %v", d.Name(), step, err)
+	}
+	return nil
+}
+
+// captureStep runs the step inside of dir and returns its stdout
+func (j *job) captureStep(ctx context.Context, dir string, step Step, args ...string) ([]byte, error) {
+	d := j.driver()
+	path, argv := d.Command(step, args...)
+	data, err := j.captureProcess(ctx, dir, path, argv...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s %s: %v", d.Name(), step, err)
+	}
+	return data, nil
+}
+
+// detectVersion returns the name and version of the driver,
+// for example: terraform 1.5.7
+func detectVersion(cfg *Config) (string, error) {
+	j := newJob(internalPrefix+"version", cfg.Dir, cfg.Dir, "", cfg.vars, &JobConfig{})
+	j.tool = cfg.Driver
+	data, err := j.captureStep(context.Background(), cfg.Dir, StepVersion)
+	if err != nil {
+		return "", err
+	}
+	v, err := cfg.Driver.ParseVersion(data)
+	if err != nil {
+		return "", err
+	}
+	return cfg.Driver.Name() + " " + v, nil
+}
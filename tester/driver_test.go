@@ -0,0 +1,145 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDriverCommand(t *testing.T) {
+	tt := map[string]struct {
+		driver       Driver
+		step         Step
+		args         []string
+		expectedPath string
+		expectedArgs []string
+	}{
+		"terraform apply": {
+			driver:       &Terraform{},
+			step:         StepApply,
+			args:         []string{"-var-file=a.tfvars"},
+			expectedPath: "terraform",
+			expectedArgs: []string{"apply", "-auto-approve", "-no-color", "-var-file=a.tfvars"},
+		},
+		"terraform path": {
+			driver:       &Terraform{Path: "/opt/terraform"},
+			step:         StepInit,
+			expectedPath: "/opt/terraform",
+			expectedArgs: []string{"init", "-no-color"},
+		},
+		"tofu show": {
+			driver:       &Tofu{},
+			step:         StepShow,
+			args:         []string{"job.tfplan"},
+			expectedPath: "tofu",
+			expectedArgs: []string{"show", "-json", "-no-color", "job.tfplan"},
+		},
+		"terragrunt output": {
+			driver:       &Terragrunt{},
+			step:         StepOutput,
+			expectedPath: "terragrunt",
+			expectedArgs: []string{"output", "-json", "-no-color"},
+		},
+		"terragrunt version": {
+			driver:       &Terragrunt{Path: "/opt/terragrunt"},
+			step:         StepVersion,
+			expectedPath: "/opt/terragrunt",
+			expectedArgs: []string{"--version"},
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			path, args := tc.driver.Command(tc.step, tc.args...)
+			if path != tc.expectedPath {
+				t.Errorf("path invalid, expected: %s, got: %s", tc.expectedPath, path)
+			}
+			if !reflect.DeepEqual(args, tc.expectedArgs) {
+				t.Errorf("args invalid, expected: %v, got: %v", tc.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestDriverParseVersion(t *testing.T) {
+	tt := map[string]struct {
+		driver   Driver
+		data     string
+		expected string
+		err      bool
+	}{
+		"terraform": {
+			driver:   &Terraform{},
+			data:     `{"terraform_version": "1.5.7", "platform": "linux_amd64"}`,
+			expected: "1.5.7",
+		},
+		"tofu": {
+			driver:   &Tofu{},
+			data:     `{"terraform_version": "1.6.0"}`,
+			expected: "1.6.0",
+		},
+		"terragrunt": {
+			driver:   &Terragrunt{},
+			data:     "terragrunt version v0.50.0\n",
+			expected: "0.50.0",
+		},
+		"invalid json": {
+			driver: &Terraform{},
+			data:   "Terraform v1.5.7",
+			err:    true,
+		},
+		"empty": {
+			driver: &Terragrunt{},
+			err:    true,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual, err := tc.driver.ParseVersion([]byte(tc.data))
+			if tc.err != (err != nil) {
+				t.Fatalf("error invalid, expected error: %t, got: %v", tc.err, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("version invalid, expected: %s, got: %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDriverParseOutputs(t *testing.T) {
+	tt := map[string]struct {
+		data     string
+		expected map[string]Output
+		err      bool
+	}{
+		"string": {
+			data: `{"bucket": {"sensitive": false, "type": "string", "value": "test"}}`,
+			expected: map[string]Output{
+				"bucket": {Type: []byte(`"string"`), Value: []byte(`"test"`)},
+			},
+		},
+		"empty": {
+			data:     `{}`,
+			expected: map[string]Output{},
+		},
+		"invalid": {
+			data: `[]`,
+			err:  true,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual, err := (&Terraform{}).ParseOutputs([]byte(tc.data))
+			if tc.err != (err != nil) {
+				t.Fatalf("error invalid, expected error: %t, got: %v", tc.err, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("outputs invalid, expected: %v, got: %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Emulator is an AWS API emulator that a job runs Terraform
+// and its tests against
+type Emulator interface {
+	// Start launches the emulator, start is used to launch any
+	// processes so they inherit the job's environment and output
+	Start(start StartFunc) error
+
+	// Ready blocks until the emulator accepts requests or
+	// returns an error if it never becomes available
+	Ready() error
+
+	// Endpoint returns the base URL of the emulator
+	Endpoint() string
+
+	// Stop terminates the emulator
+	Stop() error
+}
+
+// StartFunc starts a process inside of the job's directory with the
+// job's environment variables, the output of the process is prefixed
+// with the job name
+type StartFunc func(path string, args ...string) (*Process, error)
+
+// EmulatorFactory returns a new Emulator, it is called once for each job
+type EmulatorFactory func() Emulator
+
+// portArg is replaced with the allocated port in Binary.Args
+const portArg = "{{port}}"
+
+const urlFmt = "http://localhost:%d"
+
+// motoHealthPath is a moto endpoint that lists the models of every
+// backend, it only responds once all of the services are loaded
+const motoHealthPath = "/moto-api/data.json"
+
+// Moto runs moto_server on the first available port
+type Moto struct {
+	// Path is the path to the moto_server executable
+	// if it is not set, it will default to moto_server
+	Path string
+
+	bin *Binary
+}
+
+// NewMoto returns an Emulator running moto_server from the PATH
+func NewMoto() Emulator {
+	return &Moto{}
+}
+
+// Start launches moto_server on the first available port
+func (m *Moto) Start(start StartFunc) error {
+	path := m.Path
+	if len(path) == 0 {
+		path = "moto_server"
+	}
+	m.bin = &Binary{
+		Path:       path,
+		Args:       []string{"-p", portArg},
+		HealthPath: motoHealthPath,
+	}
+	return m.bin.Start(start)
+}
+
+// Ready waits for moto_server to accept requests
+func (m *Moto) Ready() error {
+	if m.bin == nil {
+		return errors.New("moto_server has not been started")
+	}
+	return m.bin.Ready()
+}
+
+// Endpoint returns the URL of moto_server
+func (m *Moto) Endpoint() string {
+	if m.bin == nil {
+		return ""
+	}
+	return m.bin.Endpoint()
+}
+
+// Stop terminates moto_server
+func (m *Moto) Stop() error {
+	if m.bin == nil {
+		return nil
+	}
+	return m.bin.Stop()
+}
+
+// Binary runs an emulator executable that does not require docker
+// on the first available port
+type Binary struct {
+	// Path is the path to the emulator executable
+	Path string
+
+	// Args are the arguments passed to the executable, any
+	// occurrence of {{port}} is replaced with the allocated port
+	Args []string
+
+	// HealthPath is the path requested to determine if the emulator
+	// is ready, it must respond with a successful status code. If it
+	// is not set, any response from / is accepted
+	HealthPath string
+
+	// Attempts is the number of times the emulator is polled
+	// before giving up, if it is not set, it will default to 20
+	Attempts int
+
+	port  int
+	cmd   *Process
+	start StartFunc
+}
+
+// Start launches the executable on the first available port
+func (b *Binary) Start(start StartFunc) error {
+	if len(b.Path) == 0 {
+		return errors.New("a path to the emulator executable must be provided")
+	}
+	b.start = start
+	return b.launch()
+}
+
+// launch starts the executable on a newly reserved port
+func (b *Binary) launch() error {
+	port, err := getPort()
+	if err != nil {
+		return err
+	}
+	b.port = port
+
+	args := make([]string, len(b.Args))
+	for i, a := range b.Args {
+		args[i] = strings.Replace(a, portArg, strconv.Itoa(port), -1)
+	}
+
+	b.cmd, err = b.start(b.Path, args...)
+	return err
+}
+
+// Ready polls the HealthPath of the emulator, the executable is
+// restarted on a new port if another process has taken its port
+func (b *Binary) Ready() error {
+	for attempt := 1; ; attempt++ {
+		err := probe{
+			url:      b.Endpoint() + b.HealthPath,
+			attempts: b.Attempts,
+			healthy:  len(b.HealthPath) > 0,
+			process:  b.cmd,
+		}.wait()
+		if err == nil || attempt >= portAttempts || !portTaken(b.cmd) {
+			return err
+		}
+
+		releasePort(b.port)
+		err = b.launch()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Endpoint returns the URL of the executable
+func (b *Binary) Endpoint() string {
+	return fmt.Sprintf(urlFmt, b.port)
+}
+
+// Stop terminates the executable
+func (b *Binary) Stop() error {
+	if b.port > 0 {
+		releasePort(b.port)
+	}
+
+	// if the process has already exited there is no point in
+	// continuing, so return to the caller
+	if b.cmd == nil || b.cmd.Exited() {
+		return nil
+	}
+	return kill(b.cmd.Cmd)
+}
+
+// LocalStack runs the LocalStack docker image on the first available port
+type LocalStack struct {
+	// Image is the docker image to run, if it is not
+	// set, it will default to localstack/localstack
+	Image string
+
+	// Attempts is the number of times LocalStack is polled
+	// before giving up, if it is not set, it will default to 60
+	Attempts int
+
+	name  string
+	image string
+	port  int
+	cmd   *Process
+	start StartFunc
+}
+
+// NewLocalStack returns an Emulator running localstack/localstack
+func NewLocalStack() Emulator {
+	return &LocalStack{}
+}
+
+// Start runs the LocalStack container with the edge port
+// published on the first available port
+func (l *LocalStack) Start(start StartFunc) error {
+	image := l.Image
+	if len(image) == 0 {
+		image = "localstack/localstack"
+	}
+
+	l.image = image
+	l.start = start
+	return l.launch()
+}
+
+// launch runs the container with the edge port published on a newly reserved port
+func (l *LocalStack) launch() error {
+	port, err := getPort()
+	if err != nil {
+		return err
+	}
+	l.port = port
+	l.name = "tftest-localstack-" + strconv.Itoa(port)
+
+	l.cmd, err = l.start("docker", "run", "--rm",
+		"--name", l.name,
+		"-p", strconv.Itoa(port)+":4566",
+		l.image)
+	return err
+}
+
+// Ready polls the LocalStack health endpoint, the container is
+// restarted on a new port if another process has taken its port
+func (l *LocalStack) Ready() error {
+	attempts := l.Attempts
+	if attempts <= 0 {
+		attempts = 60
+	}
+	for attempt := 1; ; attempt++ {
+		err := probe{
+			url:      l.Endpoint() + "/_localstack/health",
+			attempts: attempts,
+			healthy:  true,
+			process:  l.cmd,
+		}.wait()
+		if err == nil || attempt >= portAttempts || !portTaken(l.cmd) {
+			return err
+		}
+
+		releasePort(l.port)
+		err = l.launch()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Endpoint returns the URL of the LocalStack edge port
+func (l *LocalStack) Endpoint() string {
+	return fmt.Sprintf(urlFmt, l.port)
+}
+
+// Stop removes the LocalStack container, killing the docker
+// client alone would leave the container running, so it is
+// removed even when the client has already exited
+func (l *LocalStack) Stop() error {
+	if l.port > 0 {
+		releasePort(l.port)
+	}
+	if l.cmd == nil {
+		return nil
+	}
+	rm, err := l.start("docker", "rm", "--force", l.name)
+	if err != nil {
+		return err
+	}
+	err = rm.Wait()
+	if err != nil && !noSuchContainer(rm.Tail()) {
+		return fmt.Errorf("failed to remove container: %s -> %v", l.name, err)
+	}
+	return nil
+}
+
+// noSuchContainer returns true if the output of docker reports
+// that the container has already been removed
+func noSuchContainer(output string) bool {
+	return strings.Contains(strings.ToLower(output), "no such container")
+}
+
+// External is an emulator that is already running, it is
+// never started or stopped by Tester
+type External struct {
+	// URL is the base URL of the running emulator
+	URL string
+
+	// HealthPath is the path requested to determine if the emulator
+	// is ready, it must respond with a successful status code. If it
+	// is not set, any response from / is accepted
+	HealthPath string
+}
+
+// NewExternal returns an EmulatorFactory for an emulator
+// that is already running at rawurl
+func NewExternal(rawurl string) EmulatorFactory {
+	return func() Emulator {
+		return &External{URL: rawurl}
+	}
+}
+
+// Start does nothing as the emulator is already running
+func (e *External) Start(StartFunc) error {
+	if len(e.URL) == 0 {
+		return errors.New("a URL for the external emulator must be provided")
+	}
+	return nil
+}
+
+// Ready requests the HealthPath of the emulator
+func (e *External) Ready() error {
+	return probe{
+		url:      e.Endpoint() + e.HealthPath,
+		attempts: 1,
+		healthy:  len(e.HealthPath) > 0,
+	}.wait()
+}
+
+// Endpoint returns the URL of the emulator
+func (e *External) Endpoint() string {
+	return strings.TrimSuffix(e.URL, "/")
+}
+
+// Stop does nothing as the emulator is not owned by Tester
+func (e *External) Stop() error {
+	return nil
+}
+
+// probeTimeout limits the duration of each readiness request
+const probeTimeout = 5 * time.Second
+
+// probe polls an emulator until it is ready
+type probe struct {
+	// url is requested once per second
+	url string
+
+	// attempts is the number of requests made before giving
+	// up, if it is not set, it will default to 20
+	attempts int
+
+	// healthy requires a successful status code,
+	// otherwise any response is accepted
+	healthy bool
+
+	// process is the emulator process, the emulator is never
+	// ready once it exits, it is not set for external emulators
+	process *Process
+}
+
+// wait requests the url until it responds or the process exits
+func (p probe) wait() error {
+	attempts := p.attempts
+	if attempts <= 0 {
+		attempts = 20
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			p.sleep(1 * time.Second)
+		}
+		if p.process != nil && p.process.Exited() {
+			return fmt.Errorf("emulator exited before it was ready: %s -> %v: %s",
+				p.process.Path, p.process.Wait(), p.process.Tail())
+		}
+
+		err = p.request()
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("emulator did not respond at %s after %d attempts: %v", p.url, attempts, err)
+}
+
+// sleep waits for d or until the process exits
+func (p probe) sleep(d time.Duration) {
+	if p.process == nil {
+		time.Sleep(d)
+		return
+	}
+	select {
+	case <-p.process.done:
+	case <-time.After(d):
+	}
+}
+
+// request sends a single request to the url
+func (p probe) request() error {
+	client := &http.Client{Timeout: probeTimeout}
+	//nolint: gosec
+	resp, err := client.Get(p.url)
+	if err != nil {
+		return err
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close response body: %v", err)
+	}
+	if p.healthy && resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unhealthy response: %s", resp.Status)
+	}
+	return nil
+}
+
+// portTaken returns true if the process exited because
+// another process was already listening on its port
+func portTaken(p *Process) bool {
+	return p != nil && p.Exited() && addressInUse(p.Tail())
+}
+
+// endpointPort returns the port of the provided endpoint
+// or the default port for the scheme if none is set
+func endpointPort(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse emulator endpoint: %q -> %v", endpoint, err)
+	}
+	if port := u.Port(); len(port) > 0 {
+		return port, nil
+	}
+	if u.Scheme == "https" {
+		return "443", nil
+	}
+	return "80", nil
+}
+
+// defaultEmulator is used when Config.Emulator is not set
+var defaultEmulator EmulatorFactory = NewMoto
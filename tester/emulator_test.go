@@ -0,0 +1,255 @@
+package tester
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEndpointPort(t *testing.T) {
+	tt := map[string]struct {
+		endpoint string
+		expected string
+	}{
+		"explicit_port": {endpoint: "http://localhost:5000", expected: "5000"},
+		"http_default":  {endpoint: "http://moto", expected: "80"},
+		"https_default": {endpoint: "https://moto", expected: "443"},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual, err := endpointPort(tc.endpoint)
+			if err != nil {
+				t.Fatalf("failed to get port: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("port invalid, expected: %s, got: %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBinaryStart(t *testing.T) {
+	var args []string
+	start := func(path string, a ...string) (*Process, error) {
+		args = a
+		return nil, nil
+	}
+
+	b := &Binary{Path: "emulator", Args: []string{"--port", portArg, "--name=" + portArg}}
+	err := b.Start(start)
+	if err != nil {
+		t.Fatalf("failed to start binary: %v", err)
+	}
+
+	port := strconv.Itoa(b.port)
+	expected := []string{"--port", port, "--name=" + port}
+	if len(args) != len(expected) {
+		t.Fatalf("args invalid, expected: %v, got: %v", expected, args)
+	}
+	for i := range expected {
+		if args[i] != expected[i] {
+			t.Errorf("args invalid, expected: %v, got: %v", expected, args)
+		}
+	}
+}
+
+func TestExternal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	emu := NewExternal(srv.URL + "/")()
+	err := emu.Start(nil)
+	if err != nil {
+		t.Fatalf("failed to start external emulator: %v", err)
+	}
+	err = emu.Ready()
+	if err != nil {
+		t.Fatalf("external emulator is not ready: %v", err)
+	}
+	if emu.Endpoint() != srv.URL {
+		t.Errorf("endpoint invalid, expected: %s, got: %s", srv.URL, emu.Endpoint())
+	}
+}
+
+func TestGetProviderEndpoint(t *testing.T) {
+	data, err := getProvider(nil, []string{"s3"}, "http://moto:5000", "terraform.tfstate")
+	if err != nil {
+		t.Fatalf("failed to get provider: %v", err)
+	}
+	expected := []byte(`s3 = "http://moto:5000"`)
+	if !bytes.Contains(data, expected) {
+		t.Errorf("provider is missing endpoint: %s, got: %s", expected, data)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	exited := &job{Name: "exited", tail: &tailBuffer{}, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	p, err := exited.startProcess("go", "invalid")
+	if err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	tt := map[string]struct {
+		p        probe
+		expected string
+	}{
+		"any_response": {p: probe{url: srv.URL + "/", attempts: 1}},
+		"healthy":      {p: probe{url: srv.URL + "/health", attempts: 1, healthy: true}},
+		"unhealthy": {
+			p:        probe{url: srv.URL + "/", attempts: 2, healthy: true},
+			expected: "unhealthy response: 404 Not Found",
+		},
+		"exited": {
+			p:        probe{url: "http://localhost:1", attempts: 20, process: p},
+			expected: "emulator exited before it was ready",
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			err := tc.p.wait()
+			if len(tc.expected) == 0 {
+				if err != nil {
+					t.Errorf("emulator is not ready: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("error invalid, expected: %s, got: %v", tc.expected, err)
+			}
+		})
+	}
+
+	// the error includes the stderr of the process
+	err = probe{url: "http://localhost:1", process: p}.wait()
+	if err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("error is missing stderr, got: %v", err)
+	}
+}
+
+func TestBinaryPortTaken(t *testing.T) {
+	var (
+		calls int
+		ports []string
+		l     net.Listener
+	)
+	start := func(path string, args ...string) (*Process, error) {
+		calls++
+		ports = append(ports, args[0])
+
+		// the first process exits as if its port was taken
+		if calls == 1 {
+			p := &Process{Cmd: &exec.Cmd{Path: path}, done: make(chan struct{}), stderr: &tailBuffer{}}
+			p.stderr.add("OSError: [Errno 98] Address already in use")
+			close(p.done)
+			return p, nil
+		}
+
+		var err error
+		l, err = net.Listen("tcp", "localhost:"+args[0])
+		if err != nil {
+			return nil, err
+		}
+		go http.Serve(l, http.NotFoundHandler()) //nolint: errcheck
+		return nil, nil
+	}
+
+	b := &Binary{Path: "emulator", Args: []string{portArg}}
+	err := b.Start(start)
+	if err != nil {
+		t.Fatalf("failed to start binary: %v", err)
+	}
+	defer b.Stop() //nolint: errcheck
+
+	err = b.Ready()
+	if err != nil {
+		t.Fatalf("binary is not ready: %v", err)
+	}
+	defer l.Close()
+	if calls != 2 || ports[0] == ports[1] {
+		t.Errorf("binary was not restarted on a new port: %v", ports)
+	}
+}
+
+func TestGetPort(t *testing.T) {
+	seen := make(map[int]bool)
+	for i := 0; i < 20; i++ {
+		port, err := getPort()
+		if err != nil {
+			t.Fatalf("failed to get port: %v", err)
+		}
+		if seen[port] {
+			t.Fatalf("port was handed out twice: %d", port)
+		}
+		seen[port] = true
+	}
+	for port := range seen {
+		releasePort(port)
+	}
+}
+
+func TestLocalStackStop(t *testing.T) {
+	exited := func(err error, stderr string) *Process {
+		p := &Process{Cmd: &exec.Cmd{Path: "docker"}, done: make(chan struct{}), err: err, stderr: &tailBuffer{}}
+		p.stderr.add(stderr)
+		close(p.done)
+		return p
+	}
+
+	tt := map[string]struct {
+		rm  *Process
+		err bool
+	}{
+		"removed": {
+			rm: exited(nil, ""),
+		},
+		"already removed": {
+			rm: exited(errors.New("exit status 1"), "Error: No such container: tftest-localstack-4566"),
+		},
+		"failed": {
+			rm:  exited(errors.New("exit status 1"), "Cannot connect to the Docker daemon"),
+			err: true,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var args []string
+			l := &LocalStack{
+				name: "tftest-localstack-4566",
+				// the docker client was killed, the container may still be running
+				cmd: exited(errors.New("signal: killed"), ""),
+				start: func(path string, a ...string) (*Process, error) {
+					args = a
+					return tc.rm, nil
+				},
+			}
+
+			err := l.Stop()
+			if tc.err != (err != nil) {
+				t.Fatalf("error invalid, expected error: %t, got: %v", tc.err, err)
+			}
+			expected := "rm --force tftest-localstack-4566"
+			if strings.Join(args, " ") != expected {
+				t.Errorf("args invalid, expected: %s, got: %v", expected, args)
+			}
+		})
+	}
+}
@@ -20,3 +20,7 @@ resource "aws_kms_alias" "key" {
   name          = "alias/key"
   target_key_id = aws_kms_key.key.key_id
 }
+
+output "alias_name" {
+  value = aws_kms_alias.key.name
+}
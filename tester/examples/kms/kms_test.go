@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/GSA/grace-tftest/aws/kms"
+	"github.com/GSA/grace-tftest/tester/output"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
@@ -27,10 +28,11 @@ func TestKm(t *testing.T) {
 	}
 
 	svc := kms.New(sess)
+	outputs := output.Load(t)
 
 	alias := svc.
 		Alias.
-		Name("alias/key").
+		Name(outputs.String(t, "alias_name")).
 		Assert(t)
 
 	alias.
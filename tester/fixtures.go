@@ -0,0 +1,225 @@
+package tester
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// variableBlock matches the input variables declared in .tf files
+var variableBlock = regexp.MustCompile(`(?m)^\s*variable\s+"([^"]+)"`)
+
+// fixture is a Terraform directory that is applied to the
+// emulator of a job before the job itself is applied
+type fixture struct {
+	Name    string
+	Path    string
+	VarArgs []string
+}
+
+// key identifies the fixture so it is only applied once
+func (f fixture) key() string {
+	return f.Path + "\x00" + strings.Join(f.VarArgs, "\x00")
+}
+
+// fixtureResolver collects the fixtures of a job in the order
+// they are applied, dependencies before their dependents
+type fixtureResolver struct {
+	jobs     map[string]*job
+	visiting []string
+	seen     map[string]bool
+	fixtures []fixture
+}
+
+// resolveFixtures sets the fixtures of every job from the
+// DependsOn and Fixtures settings of their JobConfig
+func resolveFixtures(jobs []*job) error {
+	byName := make(map[string]*job, len(jobs))
+	for _, j := range jobs {
+		byName[j.Name] = j
+	}
+
+	for _, j := range jobs {
+		r := &fixtureResolver{jobs: byName, seen: make(map[string]bool)}
+		err := r.resolve(j, false)
+		if err != nil {
+			return err
+		}
+		j.Fixtures = r.fixtures
+	}
+	return nil
+}
+
+// resolve adds the dependencies and fixtures of j, followed
+// by j itself when it is a dependency of another job
+func (r *fixtureResolver) resolve(j *job, dependency bool) error {
+	for _, name := range r.visiting {
+		if name == j.Name {
+			return fmt.Errorf("dependency cycle detected: %s -> %s", strings.Join(r.visiting, " -> "), j.Name)
+		}
+	}
+	r.visiting = append(r.visiting, j.Name)
+	defer func() {
+		r.visiting = r.visiting[:len(r.visiting)-1]
+	}()
+
+	if j.Config != nil {
+		for _, name := range j.Config.DependsOn {
+			dep, ok := r.jobs[name]
+			if !ok {
+				return fmt.Errorf("unknown dependency of job: %s -> %s", j.Name, name)
+			}
+			err := r.resolve(dep, true)
+			if err != nil {
+				return err
+			}
+		}
+		for _, dir := range j.Config.Fixtures {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(j.Path, dir)
+			}
+			r.add(fixture{Name: filepath.Base(dir), Path: dir})
+		}
+	}
+
+	if dependency {
+		r.add(fixture{Name: j.Name, Path: j.Path, VarArgs: j.VarArgs})
+	}
+	return nil
+}
+
+func (r *fixtureResolver) add(f fixture) {
+	if r.seen[f.key()] {
+		return
+	}
+	r.seen[f.key()] = true
+	r.fixtures = append(r.fixtures, f)
+}
+
+// applyFixtures applies every fixture of the job to its emulator, each
+// from its own copy inside of the job workspace, the outputs of the
+// fixtures are passed to later fixtures and to the job for each
+// variable that they declare
+func (j *job) applyFixtures(ctx context.Context, cfg *Config, endpoint string) error {
+	outputs := make(map[string]Output)
+	for i, f := range j.Fixtures {
+		dir := filepath.Join(j.WorkDir, fmt.Sprintf("fixture-%d-%s", i, unsafeChars.ReplaceAllString(f.Name, "_")))
+		err := os.Mkdir(dir, 0700)
+		if err != nil {
+			return fmt.Errorf("failed to create fixture workspace: %v", err)
+		}
+		err = copyWorkspace(f.Path, dir)
+		if err != nil {
+			return err
+		}
+
+		j.printf("applying fixture: %s\n", f.Name)
+		result, err := j.applyFixture(ctx, cfg, f, dir, endpoint, outputs)
+		if err != nil {
+			return fmt.Errorf("failed to apply fixture: %s -> %v", f.Name, err)
+		}
+		for name, o := range result {
+			outputs[name] = o
+		}
+	}
+
+	args, err := outputVarArgs(j.dir(), outputs, j.VarArgs)
+	if err != nil {
+		return err
+	}
+	j.VarArgs = args
+	return nil
+}
+
+// applyFixture initializes and applies the fixture copied into dir
+// with the outputs of the fixtures applied before it and returns its
+// outputs
+func (j *job) applyFixture(ctx context.Context, cfg *Config, f fixture, dir, endpoint string, outputs map[string]Output) (map[string]Output, error) {
+	provider := filepath.Join(dir, "provider.tf")
+	services := cfg.Services
+	if len(services) == 0 {
+		services = []string{"sts"}
+	}
+	err := writeProvider(provider, j.provider(cfg), services, endpoint, "terraform.tfstate")
+	if err != nil {
+		return nil, err
+	}
+
+	err = j.runStep(ctx, dir, StepInit)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err = j.resolveServices(cfg, dir)
+	if err != nil {
+		return nil, err
+	}
+	err = writeProvider(provider, j.provider(cfg), services, endpoint, "terraform.tfstate")
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := outputVarArgs(dir, outputs, f.VarArgs)
+	if err != nil {
+		return nil, err
+	}
+	err = j.runStep(ctx, dir, StepApply, args...)
+	if err != nil {
+		return nil, err
+	}
+	return j.readOutputs(ctx, dir)
+}
+
+// declaredVariables returns the input variables declared by the .tf files in dir
+func declaredVariables(dir string) (map[string]bool, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in: %q -> %v", dir, err)
+	}
+	declared := make(map[string]bool)
+	for _, m := range matches {
+		data, err := ioutil.ReadFile(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %q -> %v", m, err)
+		}
+		for _, match := range variableBlock.FindAllSubmatch(data, -1) {
+			declared[string(match[1])] = true
+		}
+	}
+	return declared, nil
+}
+
+// outputVarArgs returns the -var arguments for the outputs that match a
+// variable declared in dir followed by args, explicit variables are
+// passed last so they take precedence
+func outputVarArgs(dir string, outputs map[string]Output, args []string) ([]string, error) {
+	declared, err := declaredVariables(dir)
+	if err != nil {
+		return nil, err
+	}
+	return append(fixtureVars(outputs, declared), args...), nil
+}
+
+// fixtureVars returns the sorted -var arguments for the outputs
+// that match a declared variable, undeclared variables are left
+// out since terraform rejects them
+func fixtureVars(outputs map[string]Output, declared map[string]bool) []string {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		if declared[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, len(names)*2)
+	for _, name := range names {
+		args = append(args, "-var", name+"="+outputValue(outputs[name].Value))
+	}
+	return args
+}
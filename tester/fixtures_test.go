@@ -0,0 +1,151 @@
+package tester
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveFixtures(t *testing.T) {
+	root := filepath.FromSlash("/tmp/jobs")
+	newTestJob := func(name string, jc *JobConfig) *job {
+		return &job{Name: name, Path: filepath.Join(root, name), Config: jc}
+	}
+
+	tt := map[string]struct {
+		jobs     []*job
+		expected map[string][]string
+		err      string
+	}{
+		"dependencies": {
+			jobs: []*job{
+				newTestJob("bucket", &JobConfig{Fixtures: []string{"../shared/kms"}}),
+				newTestJob("cloudtrail", &JobConfig{DependsOn: []string{"bucket"}, Fixtures: []string{"../shared/kms"}}),
+				newTestJob("config", &JobConfig{DependsOn: []string{"cloudtrail", "bucket"}}),
+			},
+			expected: map[string][]string{
+				"bucket":     {"shared/kms"},
+				"cloudtrail": {"shared/kms", "bucket"},
+				"config":     {"shared/kms", "bucket", "cloudtrail"},
+			},
+		},
+		"unknown": {
+			jobs: []*job{newTestJob("cloudtrail", &JobConfig{DependsOn: []string{"missing"}})},
+			err:  "unknown dependency of job: cloudtrail -> missing",
+		},
+		"cycle": {
+			jobs: []*job{
+				newTestJob("a", &JobConfig{DependsOn: []string{"b"}}),
+				newTestJob("b", &JobConfig{DependsOn: []string{"a"}}),
+			},
+			err: "dependency cycle detected: a -> b -> a",
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			err := resolveFixtures(tc.jobs)
+			if len(tc.err) > 0 {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("error invalid, expected: %s, got: %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to resolve fixtures: %v", err)
+			}
+			for _, j := range tc.jobs {
+				var actual []string
+				for _, f := range j.Fixtures {
+					rel, err := filepath.Rel(root, f.Path)
+					if err != nil {
+						t.Fatalf("failed to resolve relative path: %v", err)
+					}
+					actual = append(actual, filepath.ToSlash(rel))
+				}
+				if !reflect.DeepEqual(actual, tc.expected[j.Name]) {
+					t.Errorf("fixtures invalid: %s, expected: %v, got: %v", j.Name, tc.expected[j.Name], actual)
+				}
+			}
+		})
+	}
+}
+
+func TestFixtureVars(t *testing.T) {
+	outputs := map[string]Output{
+		"bucket_name": {Value: json.RawMessage(`"logs"`)},
+		"kms_arns":    {Value: json.RawMessage(`["a","b"]`)},
+		"unused":      {Value: json.RawMessage(`1`)},
+	}
+	declared := map[string]bool{"bucket_name": true, "kms_arns": true}
+
+	expected := []string{"-var", "bucket_name=logs", "-var", `kms_arns=["a","b"]`}
+	actual := fixtureVars(outputs, declared)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("vars invalid, expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestDeclaredVariables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "variables")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tf := strings.Join([]string{
+		`variable "bucket_name" {}`,
+		`  variable "kms_arns" {`,
+		`    type = list(string)`,
+		`  }`,
+		`# variable "commented"`,
+		`output "bucket_name" {`,
+		`  value = var.bucket_name`,
+		`}`,
+	}, "\n")
+	err = ioutil.WriteFile(filepath.Join(dir, "variables.tf"), []byte(tf), 0600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	actual, err := declaredVariables(dir)
+	if err != nil {
+		t.Fatalf("failed to read variables: %v", err)
+	}
+	expected := map[string]bool{"bucket_name": true, "kms_arns": true}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("variables invalid, expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestOutputVarArgs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "variables")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a fixture that depends on the outputs of an earlier fixture
+	err = ioutil.WriteFile(filepath.Join(dir, "variables.tf"), []byte(`variable "bucket_name" {}`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	outputs := map[string]Output{
+		"bucket_name": {Value: json.RawMessage(`"logs"`)},
+		"trail_arn":   {Value: json.RawMessage(`"arn"`)},
+	}
+
+	actual, err := outputVarArgs(dir, outputs, []string{"-var", "bucket_name=override"})
+	if err != nil {
+		t.Fatalf("failed to get var args: %v", err)
+	}
+	expected := []string{"-var", "bucket_name=logs", "-var", "bucket_name=override"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("args invalid, expected: %v, got: %v", expected, actual)
+	}
+}
@@ -0,0 +1,185 @@
+package tester
+
+import (
+	"context"
+	"fmt"
+)
+
+// HookPoint is the point of a job that a hook is run at
+type HookPoint string
+
+// the points of a job that hooks are able to run at
+const (
+	// BeforeEmulator runs before the emulator is started, it is
+	// not run in plan-only mode
+	BeforeEmulator HookPoint = "before_emulator"
+
+	// AfterEmulator runs once the emulator is ready, before the
+	// dependencies and fixtures of the job are applied, it is not
+	// run in plan-only mode
+	AfterEmulator HookPoint = "after_emulator"
+
+	// BeforeApply runs after terraform init, before terraform apply
+	// or before terraform plan in plan-only mode
+	BeforeApply HookPoint = "before_apply"
+
+	// AfterApply runs once terraform apply succeeds and the
+	// outputs of the job have been read, or once the plan has
+	// been written in plan-only mode
+	AfterApply HookPoint = "after_apply"
+
+	// BeforeTest runs before go test
+	BeforeTest HookPoint = "before_test"
+
+	// AfterTest runs once go test succeeds
+	AfterTest HookPoint = "after_test"
+
+	// OnFailure runs when the job fails, before the emulator is
+	// stopped, HookJob.Err contains the error of the job
+	OnFailure HookPoint = "on_failure"
+)
+
+// hookPoints are the valid hook points
+var hookPoints = map[HookPoint]bool{
+	BeforeEmulator: true,
+	AfterEmulator:  true,
+	BeforeApply:    true,
+	AfterApply:     true,
+	BeforeTest:     true,
+	AfterTest:      true,
+	OnFailure:      true,
+}
+
+// HookJob describes the job that a hook is run for
+type HookJob struct {
+	// Name is the name of the job
+	Name string
+
+	// Dir is the workspace that terraform is executed from
+	Dir string
+
+	// Env contains the environment variables of the job, for
+	// example: MOTO_PORT and TFTEST_ENDPOINT once the emulator
+	// has started
+	Env []string
+
+	// Point is the hook point that is being run
+	Point HookPoint
+
+	// Err is the error of the job for OnFailure hooks
+	Err error
+}
+
+// HookFunc is a hook implemented in Go, returning an error fails the job
+type HookFunc func(ctx context.Context, hj *HookJob) error
+
+// Hook is a shell command or a Go func that is run at a point of every
+// job, a command that exits non-zero or a func that returns an error
+// fails the job
+type Hook struct {
+	// Point is the point of the job that the hook is run at
+	Point HookPoint `json:"point"`
+
+	// Command is run by the shell inside of the job workspace
+	// with the environment of the job, for example: tflint
+	Command string `json:"command"`
+
+	// Func is called instead of running Command
+	Func HookFunc `json:"-"`
+}
+
+// validateHooks returns an error if any hook has an unknown point or
+// does not have exactly one of Command and Func
+func validateHooks(hooks []Hook) error {
+	for _, h := range hooks {
+		if !hookPoints[h.Point] {
+			return fmt.Errorf("invalid hook point: %q", h.Point)
+		}
+		if (len(h.Command) > 0) == (h.Func != nil) {
+			return fmt.Errorf("a %s hook must have either a command or a func", h.Point)
+		}
+	}
+	return nil
+}
+
+// hooks returns the hooks of Tester followed by the hooks of the job
+// that are run at point
+func (j *job) hooks(cfg *Config, point HookPoint) []Hook {
+	var hooks []Hook
+	for _, h := range cfg.Hooks {
+		if h.Point == point {
+			hooks = append(hooks, h)
+		}
+	}
+	if j.Config != nil {
+		for _, h := range j.Config.Hooks {
+			if h.Point == point {
+				hooks = append(hooks, h)
+			}
+		}
+	}
+	return hooks
+}
+
+// runHooks runs the hooks of point in order, stopping at the first
+// hook that fails
+func (j *job) runHooks(ctx context.Context, cfg *Config, point HookPoint, jobErr error) error {
+	hooks := j.hooks(cfg, point)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	return j.runPhase(ctx, phaseHooks, cfg.Timeouts.Hooks, func(ctx context.Context) error {
+		for _, h := range hooks {
+			err := j.runHook(ctx, h, jobErr)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// runHook runs a single hook inside of the job workspace
+func (j *job) runHook(ctx context.Context, h Hook, jobErr error) error {
+	if h.Func != nil {
+		j.mu.Lock()
+		hj := &HookJob{
+			Name:  j.Name,
+			Dir:   j.dir(),
+			Env:   append([]string{}, j.Env...),
+			Point: h.Point,
+			Err:   jobErr,
+		}
+		j.mu.Unlock()
+
+		err := h.Func(ctx, hj)
+		if err != nil {
+			return fmt.Errorf("failed to run %s hook: %v", h.Point, err)
+		}
+		return nil
+	}
+
+	j.printf("running %s hook: %s\n", h.Point, h.Command)
+	path, args := shellCommand(h.Command)
+	p, err := j.startProcessContext(ctx, path, args...)
+	if err != nil {
+		return fmt.Errorf("failed to run %s hook: %q -> %v", h.Point, h.Command, err)
+	}
+	err = p.Wait()
+	if err != nil {
+		return fmt.Errorf("failed to run %s hook: %q -> %v", h.Point, h.Command, err)
+	}
+	return nil
+}
+
+// runFailureHooks runs the OnFailure hooks of a job that failed with
+// jobErr, the job may have run out of time so they are given their
+// own timeout
+func (j *job) runFailureHooks(cfg *Config, jobErr error) error {
+	err := j.runHooks(context.Background(), cfg, OnFailure, jobErr)
+	if err != nil {
+		return fmt.Errorf("%v; %v", jobErr, err)
+	}
+	return jobErr
+}
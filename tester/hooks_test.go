@@ -0,0 +1,151 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestValidateHooks(t *testing.T) {
+	fn := func(context.Context, *HookJob) error { return nil }
+
+	tt := map[string]struct {
+		hooks []Hook
+		err   bool
+	}{
+		"command": {
+			hooks: []Hook{{Point: BeforeApply, Command: "tflint"}},
+		},
+		"func": {
+			hooks: []Hook{{Point: OnFailure, Func: fn}},
+		},
+		"unknown point": {
+			hooks: []Hook{{Point: "after_destroy", Command: "tflint"}},
+			err:   true,
+		},
+		"empty": {
+			hooks: []Hook{{Point: BeforeTest}},
+			err:   true,
+		},
+		"both": {
+			hooks: []Hook{{Point: BeforeTest, Command: "tflint", Func: fn}},
+			err:   true,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			err := validateHooks(tc.hooks)
+			if tc.err != (err != nil) {
+				t.Errorf("error invalid, expected error: %t, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestJobHooks(t *testing.T) {
+	cfg := &Config{Hooks: []Hook{
+		{Point: BeforeApply, Command: "config"},
+		{Point: AfterApply, Command: "after"},
+	}}
+	j := &job{Config: &JobConfig{Hooks: []Hook{
+		{Point: BeforeApply, Command: "job"},
+	}}}
+
+	var actual []string
+	for _, h := range j.hooks(cfg, BeforeApply) {
+		actual = append(actual, h.Command)
+	}
+	expected := "config,job"
+	if strings.Join(actual, ",") != expected {
+		t.Errorf("hooks invalid, expected: %s, got: %v", expected, actual)
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands are run by sh")
+	}
+
+	dir, err := ioutil.TempDir("", "hooks")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tt := map[string]struct {
+		hooks    []Hook
+		jobErr   error
+		expected string
+		err      bool
+	}{
+		"command env": {
+			hooks:    []Hook{{Point: BeforeTest, Command: `printf %s "$MOTO_PORT" > seeded`}},
+			expected: "5000",
+		},
+		"command failure": {
+			hooks: []Hook{{Point: BeforeTest, Command: "exit 3"}},
+			err:   true,
+		},
+		"func": {
+			hooks: []Hook{{Point: OnFailure, Func: func(ctx context.Context, hj *HookJob) error {
+				data := string(hj.Point) + ": " + hj.Err.Error()
+				return ioutil.WriteFile(filepath.Join(hj.Dir, "seeded"), []byte(data), 0600)
+			}}},
+			jobErr:   errors.New("apply failed"),
+			expected: "on_failure: apply failed",
+		},
+		"func failure": {
+			hooks: []Hook{{Point: BeforeTest, Func: func(context.Context, *HookJob) error {
+				return errors.New("seed failed")
+			}}},
+			err: true,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			_ = os.Remove(filepath.Join(dir, "seeded"))
+
+			j := newJob(name, dir, dir, "", []string{"MOTO_PORT=5000"}, &JobConfig{})
+			j.Stdout, j.Stderr = ioutil.Discard, ioutil.Discard
+			cfg := &Config{Hooks: tc.hooks}
+			err := j.runHooks(context.Background(), cfg, tc.hooks[0].Point, tc.jobErr)
+			if tc.err != (err != nil) {
+				t.Fatalf("error invalid, expected error: %t, got: %v", tc.err, err)
+			}
+			if tc.err {
+				return
+			}
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "seeded"))
+			if err != nil {
+				t.Fatalf("failed to read hook output: %v", err)
+			}
+			if string(data) != tc.expected {
+				t.Errorf("hook output invalid, expected: %q, got: %q", tc.expected, data)
+			}
+		})
+	}
+}
+
+func TestRunFailureHooks(t *testing.T) {
+	cfg := &Config{Hooks: []Hook{{Point: OnFailure, Func: func(context.Context, *HookJob) error {
+		return errors.New("dump failed")
+	}}}}
+	j := newJob("failure", "", "", "", nil, &JobConfig{})
+	j.Stdout, j.Stderr = ioutil.Discard, ioutil.Discard
+
+	err := j.runFailureHooks(cfg, errors.New("apply failed"))
+	expected := "apply failed; failed to run on_failure hook: dump failed"
+	if err == nil || err.Error() != expected {
+		t.Errorf("error invalid, expected: %s, got: %v", expected, err)
+	}
+}
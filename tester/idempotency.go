@@ -0,0 +1,139 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GSA/grace-tftest/tfplan"
+)
+
+// planChangesExitCode is returned by terraform plan -detailed-exitcode
+// when the plan succeeded and contains changes
+const planChangesExitCode = 2
+
+// idempotencyPlan is the name of the plan file written to the workspace
+const idempotencyPlan = "idempotency.tfplan"
+
+// checkIdempotency plans the job again after it has been applied,
+// the job fails if the plan is not empty since the module would
+// never converge
+func (j *job) checkIdempotency(ctx context.Context) error {
+	plan := filepath.Join(j.dir(), idempotencyPlan)
+	d := j.driver()
+	path, args := d.Command(StepPlan, append([]string{"-detailed-exitcode", "-out=" + plan}, j.VarArgs...)...)
+	p, err := j.startProcessContext(ctx, path, args...)
+	if err != nil {
+		return fmt.Errorf("failed to run %s plan: %v", d.Name(), err)
+	}
+	err = p.Wait()
+	if err == nil {
+		return nil
+	}
+	if xerr, ok := err.(*exec.ExitError); !ok || xerr.ExitCode() != planChangesExitCode {
+		return fmt.Errorf("failed to wait for %s plan: %v", d.Name(), err)
+	}
+
+	data, err := j.captureStep(ctx, j.dir(), StepShow, plan)
+	if err != nil {
+		return err
+	}
+	changes, err := parseChanges(data)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%s is not idempotent, the plan after apply contains changes:\n%s", d.Name(), strings.Join(changes, "\n"))
+}
+
+// parseChanges returns a line for each resource changed by the plan
+// listing its actions and the attributes that would change
+func parseChanges(data []byte) ([]string, error) {
+	var plan tfplan.Plan
+	err := json.Unmarshal(data, &plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse terraform plan: %v", err)
+	}
+
+	var changes []string
+	for _, rc := range plan.ResourceChanges {
+		actions := strings.Join(rc.Change.Actions, ", ")
+		if actions == "no-op" || actions == "read" {
+			continue
+		}
+		line := fmt.Sprintf("  %s (%s)", rc.Address, actions)
+
+		attrs := changedAttributes(rc.Change.Before, rc.Change.After, rc.Change.AfterUnknown)
+		if len(attrs) > 0 {
+			line += ": " + strings.Join(attrs, ", ")
+		}
+		changes = append(changes, line)
+	}
+	return changes, nil
+}
+
+// changedAttributes returns the sorted paths of the attributes that
+// differ between before and after or are unknown until apply
+func changedAttributes(before, after, unknown interface{}) []string {
+	b := make(map[string]interface{})
+	a := make(map[string]interface{})
+	u := make(map[string]interface{})
+	flatten("", before, b)
+	flatten("", after, a)
+	flatten("", unknown, u)
+
+	changed := make(map[string]bool)
+	for k, v := range b {
+		if !reflect.DeepEqual(v, a[k]) {
+			changed[k] = true
+		}
+	}
+	for k, v := range a {
+		if !reflect.DeepEqual(v, b[k]) {
+			changed[k] = true
+		}
+	}
+	for k, v := range u {
+		if v == true {
+			changed[k] = true
+		}
+	}
+
+	attrs := make([]string, 0, len(changed))
+	for k := range changed {
+		attrs = append(attrs, k)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// flatten adds every leaf value of v to out using dotted paths,
+// for example: tags.Name or ingress.0.cidr_blocks.0
+func flatten(prefix string, v interface{}, out map[string]interface{}) {
+	join := func(key string) string {
+		if len(prefix) == 0 {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			flatten(join(k), e, out)
+		}
+	case []interface{}:
+		for i, e := range t {
+			flatten(join(strconv.Itoa(i)), e, out)
+		}
+	default:
+		if len(prefix) > 0 {
+			out[prefix] = v
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChanges(t *testing.T) {
+	plan := []byte(`{
+  "format_version": "0.1",
+  "resource_changes": [
+    {
+      "address": "aws_s3_bucket.logs",
+      "change": {
+        "actions": ["update"],
+        "before": {"bucket": "logs", "tags": {"Name": "logs"}, "policy": "{\"a\":1,\"b\":2}"},
+        "after": {"bucket": "logs", "tags": {"Name": "logs", "Owner": "grace"}, "policy": "{\"b\":2,\"a\":1}"},
+        "after_unknown": {}
+      }
+    },
+    {
+      "address": "aws_kms_key.key",
+      "change": {
+        "actions": ["no-op"],
+        "before": {"description": "key"},
+        "after": {"description": "key"}
+      }
+    },
+    {
+      "address": "aws_security_group.sg",
+      "change": {
+        "actions": ["delete", "create"],
+        "before": {"ingress": [{"cidr_blocks": ["10.0.0.0/8"]}], "id": "sg-1"},
+        "after": {"ingress": [{"cidr_blocks": ["10.0.0.0/16"]}]},
+        "after_unknown": {"id": true}
+      }
+    }
+  ]
+}`)
+
+	expected := []string{
+		"  aws_s3_bucket.logs (update): policy, tags.Owner",
+		"  aws_security_group.sg (delete, create): id, ingress.0.cidr_blocks.0",
+	}
+	actual, err := parseChanges(plan)
+	if err != nil {
+		t.Fatalf("failed to parse changes: %v", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("changes invalid, expected: %q, got: %q", expected, actual)
+	}
+}
+
+func TestParseChangesInvalid(t *testing.T) {
+	_, err := parseChanges([]byte("not json"))
+	if err == nil {
+		t.Errorf("expected an error for an invalid plan")
+	}
+}
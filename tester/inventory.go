@@ -0,0 +1,304 @@
+package tester
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/configservice"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// inventory is a list of resource identifiers keyed by
+// the Terraform AWS Provider custom endpoint name
+type inventory map[string][]string
+
+// lister returns the identifiers of every resource
+// of a single service that exists in the emulator
+type lister func(sess *session.Session) ([]string, error)
+
+// listers contains the services that can be enumerated
+// keyed by the Terraform AWS Provider custom endpoint name
+var listers = map[string]lister{
+	"cloudtrail":       listTrails,
+	"cloudwatch":       listAlarms,
+	"cloudwatchevents": listRules,
+	"cloudwatchlogs":   listLogGroups,
+	"configservice":    listConfigRules,
+	"iam":              listIAM,
+	"kms":              listKeys,
+	"lambda":           listFunctions,
+	"s3":               listBuckets,
+	"sns":              listTopics,
+}
+
+// newSession returns a session pointed at the emulator endpoint
+// using the region and credentials from the job's environment
+func newSession(endpoint string, env []string) (*session.Session, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(endpoint),
+		Region:           aws.String(envValue(env, "AWS_REGION")),
+		DisableSSL:       aws.Bool(strings.HasPrefix(endpoint, "http://")),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials: credentials.NewStaticCredentials(
+			envValue(env, "AWS_ACCESS_KEY_ID"),
+			envValue(env, "AWS_SECRET_ACCESS_KEY"),
+			"",
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session for: %s -> %v", endpoint, err)
+	}
+	return sess, nil
+}
+
+// envValue returns the last value of key in a KEY=VALUE slice
+func envValue(env []string, key string) string {
+	var value string
+	for _, e := range env {
+		if strings.HasPrefix(e, key+"=") {
+			value = strings.TrimPrefix(e, key+"=")
+		}
+	}
+	return value
+}
+
+// listedServices returns the services that have a lister,
+// limited to services if any are provided
+func listedServices(services []string) []string {
+	var result []string
+	if len(services) == 0 {
+		for s := range listers {
+			result = append(result, s)
+		}
+	}
+	for _, s := range services {
+		if _, ok := listers[s]; ok {
+			result = append(result, s)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// takeInventory lists the resources of each service in the emulator
+func takeInventory(sess *session.Session, services []string) (inventory, error) {
+	inv := make(inventory)
+	for _, s := range listedServices(services) {
+		ids, err := listers[s](sess)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s resources: %v", s, err)
+		}
+		sort.Strings(ids)
+		inv[s] = ids
+	}
+	return inv, nil
+}
+
+// diff returns the resources in i that are not in before
+func (i inventory) diff(before inventory) inventory {
+	result := make(inventory)
+	for s, ids := range i {
+		known := make(map[string]bool, len(before[s]))
+		for _, id := range before[s] {
+			known[id] = true
+		}
+		for _, id := range ids {
+			if !known[id] {
+				result[s] = append(result[s], id)
+			}
+		}
+	}
+	return result
+}
+
+func (i inventory) String() string {
+	services := make([]string, 0, len(i))
+	for s := range i {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+
+	parts := make([]string, 0, len(services))
+	for _, s := range services {
+		parts = append(parts, fmt.Sprintf("%s: %s", s, strings.Join(i[s], ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func listTrails(sess *session.Session) ([]string, error) {
+	out, err := cloudtrail.New(sess).DescribeTrails(&cloudtrail.DescribeTrailsInput{})
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, t := range out.TrailList {
+		ids = append(ids, aws.StringValue(t.TrailARN))
+	}
+	return ids, nil
+}
+
+func listAlarms(sess *session.Session) ([]string, error) {
+	var ids []string
+	err := cloudwatch.New(sess).DescribeAlarmsPages(&cloudwatch.DescribeAlarmsInput{},
+		func(out *cloudwatch.DescribeAlarmsOutput, lastPage bool) bool {
+			for _, a := range out.MetricAlarms {
+				ids = append(ids, aws.StringValue(a.AlarmArn))
+			}
+			return !lastPage
+		})
+	return ids, err
+}
+
+// listRules follows NextToken as the sdk has no pages helper for ListRules
+func listRules(sess *session.Session) ([]string, error) {
+	svc := cloudwatchevents.New(sess)
+	input := &cloudwatchevents.ListRulesInput{}
+	var ids []string
+	for {
+		out, err := svc.ListRules(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range out.Rules {
+			ids = append(ids, aws.StringValue(r.Arn))
+		}
+		if len(aws.StringValue(out.NextToken)) == 0 {
+			return ids, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+func listLogGroups(sess *session.Session) ([]string, error) {
+	var ids []string
+	err := cloudwatchlogs.New(sess).DescribeLogGroupsPages(&cloudwatchlogs.DescribeLogGroupsInput{},
+		func(out *cloudwatchlogs.DescribeLogGroupsOutput, lastPage bool) bool {
+			for _, g := range out.LogGroups {
+				ids = append(ids, aws.StringValue(g.LogGroupName))
+			}
+			return !lastPage
+		})
+	return ids, err
+}
+
+// listConfigRules follows NextToken as the sdk has
+// no pages helper for DescribeConfigRules
+func listConfigRules(sess *session.Session) ([]string, error) {
+	svc := configservice.New(sess)
+	input := &configservice.DescribeConfigRulesInput{}
+	var ids []string
+	for {
+		out, err := svc.DescribeConfigRules(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range out.ConfigRules {
+			ids = append(ids, aws.StringValue(r.ConfigRuleArn))
+		}
+		if len(aws.StringValue(out.NextToken)) == 0 {
+			return ids, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+func listIAM(sess *session.Session) ([]string, error) {
+	svc := iam.New(sess)
+	var ids []string
+	err := svc.ListRolesPages(&iam.ListRolesInput{},
+		func(out *iam.ListRolesOutput, lastPage bool) bool {
+			for _, r := range out.Roles {
+				ids = append(ids, aws.StringValue(r.Arn))
+			}
+			return !lastPage
+		})
+	if err != nil {
+		return nil, err
+	}
+	// only customer managed policies, AWS managed
+	// policies are always present in the emulator
+	err = svc.ListPoliciesPages(&iam.ListPoliciesInput{Scope: aws.String(iam.PolicyScopeTypeLocal)},
+		func(out *iam.ListPoliciesOutput, lastPage bool) bool {
+			for _, p := range out.Policies {
+				ids = append(ids, aws.StringValue(p.Arn))
+			}
+			return !lastPage
+		})
+	return ids, err
+}
+
+func listKeys(sess *session.Session) ([]string, error) {
+	svc := kms.New(sess)
+	var keys []*kms.KeyListEntry
+	err := svc.ListKeysPages(&kms.ListKeysInput{},
+		func(out *kms.ListKeysOutput, lastPage bool) bool {
+			keys = append(keys, out.Keys...)
+			return !lastPage
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, k := range keys {
+		out, err := svc.DescribeKey(&kms.DescribeKeyInput{KeyId: k.KeyId})
+		if err != nil {
+			return nil, err
+		}
+		// keys scheduled for deletion have been destroyed
+		// as far as Terraform is concerned
+		if aws.StringValue(out.KeyMetadata.KeyState) == kms.KeyStatePendingDeletion {
+			continue
+		}
+		ids = append(ids, aws.StringValue(k.KeyArn))
+	}
+	return ids, nil
+}
+
+func listFunctions(sess *session.Session) ([]string, error) {
+	var ids []string
+	err := lambda.New(sess).ListFunctionsPages(&lambda.ListFunctionsInput{},
+		func(out *lambda.ListFunctionsOutput, lastPage bool) bool {
+			for _, f := range out.Functions {
+				ids = append(ids, aws.StringValue(f.FunctionArn))
+			}
+			return !lastPage
+		})
+	return ids, err
+}
+
+func listBuckets(sess *session.Session) ([]string, error) {
+	out, err := s3.New(sess).ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, b := range out.Buckets {
+		ids = append(ids, aws.StringValue(b.Name))
+	}
+	return ids, nil
+}
+
+func listTopics(sess *session.Session) ([]string, error) {
+	var ids []string
+	err := sns.New(sess).ListTopicsPages(&sns.ListTopicsInput{},
+		func(out *sns.ListTopicsOutput, lastPage bool) bool {
+			for _, t := range out.Topics {
+				ids = append(ids, aws.StringValue(t.TopicArn))
+			}
+			return !lastPage
+		})
+	return ids, err
+}
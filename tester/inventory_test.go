@@ -0,0 +1,98 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestInventoryDiff(t *testing.T) {
+	tt := map[string]struct {
+		before   inventory
+		after    inventory
+		expected inventory
+	}{
+		"no_leaks": {
+			before:   inventory{"s3": {"a"}},
+			after:    inventory{"s3": {"a"}, "kms": nil},
+			expected: inventory{},
+		},
+		"leaks": {
+			before:   inventory{"s3": {"a"}},
+			after:    inventory{"s3": {"a", "b"}, "kms": {"key"}},
+			expected: inventory{"s3": {"b"}, "kms": {"key"}},
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual := tc.after.diff(tc.before)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("diff invalid, expected: %v, got: %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestInventoryString(t *testing.T) {
+	inv := inventory{"s3": {"a", "b"}, "kms": {"key"}}
+	expected := "kms: key; s3: a, b"
+	if actual := inv.String(); actual != expected {
+		t.Errorf("string invalid, expected: %s, got: %s", expected, actual)
+	}
+}
+
+func TestListedServices(t *testing.T) {
+	actual := listedServices([]string{"sns", "ec2", "s3"})
+	expected := []string{"s3", "sns"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("services invalid, expected: %v, got: %v", expected, actual)
+	}
+	if len(listedServices(nil)) != len(listers) {
+		t.Errorf("expected every lister when no services are provided")
+	}
+}
+
+func TestEnvValue(t *testing.T) {
+	env := []string{"AWS_REGION=us-east-1", "OTHER=x", "AWS_REGION=us-west-2"}
+	if actual := envValue(env, "AWS_REGION"); actual != "us-west-2" {
+		t.Errorf("value invalid, expected: us-west-2, got: %s", actual)
+	}
+	if actual := envValue(env, "MISSING"); actual != "" {
+		t.Errorf("value invalid, expected empty string, got: %s", actual)
+	}
+}
+
+func TestListRulesPages(t *testing.T) {
+	pages := map[string]string{
+		"":     `{"Rules": [{"Arn": "arn:rule/a"}], "NextToken": "next"}`,
+		"next": `{"Rules": [{"Arn": "arn:rule/b"}]}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input := struct{ NextToken string }{}
+		err := json.NewDecoder(r.Body).Decode(&input)
+		if err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		fmt.Fprint(w, pages[input.NextToken])
+	}))
+	defer srv.Close()
+
+	sess, err := newSession(srv.URL, []string{"AWS_REGION=us-east-1", "AWS_ACCESS_KEY_ID=a", "AWS_SECRET_ACCESS_KEY=b"})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	actual, err := listRules(sess)
+	if err != nil {
+		t.Fatalf("failed to list rules: %v", err)
+	}
+	expected := []string{"arn:rule/a", "arn:rule/b"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("rules invalid, expected: %v, got: %v", expected, actual)
+	}
+}
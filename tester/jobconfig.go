@@ -0,0 +1,81 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// JobConfigFile is the name of the optional file inside of a job
+// directory that contains the JSON encoded JobConfig for the job
+const JobConfigFile = "tftest.json"
+
+// JobConfig contains the settings of a single job
+type JobConfig struct {
+	// Tags are used to select the job using Config.Tags
+	// and Config.ExcludeTags, for example: slow or kms
+	Tags []string `json:"tags"`
+
+	// Matrix runs the job once for each entry, each run is reported
+	// as its own job. If it is not set, an entry is created for each
+	// *.tfvars file in the job directory that terraform does not
+	// load automatically
+	Matrix []MatrixEntry `json:"matrix"`
+
+	// Provider replaces Config.Provider for the job
+	Provider *ProviderConfig `json:"provider"`
+
+	// DependsOn are the names of jobs whose Terraform is applied to the
+	// emulator before the job, along with their own dependencies
+	DependsOn []string `json:"depends_on"`
+
+	// Fixtures are Terraform directories, relative to the job directory,
+	// that are applied to the emulator before the job. The outputs of
+	// every dependency and fixture are passed to the fixtures applied
+	// after it and to the job using -var for each variable they declare
+	Fixtures []string `json:"fixtures"`
+
+	// PlanOnly stops the job after terraform plan as if
+	// Config.PlanOnly was set, for modules using services
+	// that the emulator does not implement
+	PlanOnly bool `json:"plan_only"`
+
+	// Weight is the number of job slots used by the job, heavy jobs
+	// use more slots so fewer jobs run alongside them. If it is not
+	// set, it will default to 1
+	Weight int `json:"weight"`
+
+	// Retries replaces Config.Retries for the job, for
+	// example: 0 disables retries of a job that is never flaky
+	Retries *int `json:"retries"`
+
+	// Hooks are shell commands that are run at points of the
+	// job after the hooks of Config.Hooks
+	Hooks []Hook `json:"hooks"`
+}
+
+// readJobConfig reads the JobConfigFile inside of dir, a missing
+// file results in an empty JobConfig
+func readJobConfig(dir string) (*JobConfig, error) {
+	path := filepath.Join(dir, JobConfigFile)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if ignoreNotExistsErr(err) == nil {
+			return &JobConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to read job config: %q -> %v", path, err)
+	}
+
+	jc := &JobConfig{}
+	err = json.Unmarshal(data, jc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse job config: %q -> %v", path, err)
+	}
+
+	err = validateHooks(jc.Hooks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse job config: %q -> %v", path, err)
+	}
+	return jc, nil
+}
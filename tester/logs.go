@@ -0,0 +1,200 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// the names of the log files written for each job
+const (
+	emulatorLog  = "emulator.log"
+	terraformLog = "terraform.log"
+	testLog      = "test.log"
+	hooksLog     = "hooks.log"
+)
+
+// logName returns the log file that receives the output
+// of the processes started during phase
+func logName(phase string) string {
+	switch phase {
+	case phaseEmulator:
+		return emulatorLog
+	case phaseTest:
+		return testLog
+	case phaseHooks:
+		return hooksLog
+	default:
+		return terraformLog
+	}
+}
+
+// logFiles are the log files of a job inside of dir, each file is
+// created when it is first written to, writes after close are dropped
+// so processes that are still exiting do not report errors
+type logFiles struct {
+	dir string
+
+	mu     sync.Mutex
+	files  map[string]*os.File
+	closed bool
+}
+
+func newLogFiles(dir string) *logFiles {
+	return &logFiles{dir: dir, files: make(map[string]*os.File)}
+}
+
+// write appends line to the log file name
+func (l *logFiles) write(name, line string) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+
+	f, ok := l.files[name]
+	if !ok {
+		err := os.MkdirAll(l.dir, 0700)
+		if err != nil {
+			return fmt.Errorf("failed to create log directory: %q -> %v", l.dir, err)
+		}
+		path := filepath.Join(l.dir, name)
+		// append so a replaced pool emulator keeps the previous output
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create log file: %q -> %v", path, err)
+		}
+		l.files[name] = f
+	}
+
+	_, err := fmt.Fprintln(f, line)
+	if err != nil {
+		return fmt.Errorf("failed to write to log file: %q -> %v", f.Name(), err)
+	}
+	return nil
+}
+
+// close closes every log file that has been written to
+func (l *logFiles) close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+
+	var errs []string
+	for _, f := range l.files {
+		err := f.Close()
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close log files: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// prepareArtifacts creates a temporary artifacts directory when
+// Config.ArtifactsDir is not set, it is removed after the run if
+// every job succeeds, unless the merged coverage profile is in it
+func prepareArtifacts(cfg *Config) error {
+	if len(cfg.ArtifactsDir) > 0 {
+		dir, err := filepath.Abs(cfg.ArtifactsDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve absolute path to %s -> %v", cfg.ArtifactsDir, err)
+		}
+		cfg.ArtifactsDir = dir
+	} else {
+		dir, err := ioutil.TempDir(cfg.WorkspaceDir, "tftest-artifacts-")
+		if err != nil {
+			return fmt.Errorf("failed to create artifacts directory: %v", err)
+		}
+		cfg.ArtifactsDir = dir
+		cfg.removeArtifacts = true
+	}
+
+	// the merged profile is written next to the profile of
+	// each job rather than into the source tree
+	if cfg.Coverage && len(cfg.CoverageFile) == 0 {
+		cfg.CoverageFile = filepath.Join(cfg.ArtifactsDir, coverageFile)
+		cfg.removeArtifacts = false
+	}
+	return nil
+}
+
+// internalPrefix starts the name of the jobs that the tester runs for
+// itself, go ignores directories starting with it so the log directory
+// of such a job never collides with the one of a test directory
+const internalPrefix = "_"
+
+// logDir returns the directory inside of the artifacts
+// directory that the log files of the job are written to
+func logDir(cfg *Config, name string) string {
+	return filepath.Join(cfg.ArtifactsDir, unsafeChars.ReplaceAllString(name, "_"))
+}
+
+// writeArtifact writes data to path inside of the artifacts
+// directory, creating the directory of the job when needed
+func writeArtifact(path string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %q -> %v", filepath.Dir(path), err)
+	}
+	err = ioutil.WriteFile(path, data, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write artifact: %q -> %v", path, err)
+	}
+	return nil
+}
+
+// setLogs directs the output of the job to its log files and to the
+// writers of Tester, which discard the output unless Config.Stream is set
+func (j *job) setLogs(cfg *Config) {
+	if cfg.stdout != nil {
+		j.Stdout, j.Stderr = cfg.stdout, cfg.stderr
+	}
+	if len(cfg.ArtifactsDir) == 0 {
+		return
+	}
+	j.LogDir = logDir(cfg, j.Name)
+	j.logs = newLogFiles(j.LogDir)
+}
+
+// printf writes a message about the job to its output and log files
+func (j *job) printf(format string, args ...interface{}) {
+	msg := strings.TrimRight(fmt.Sprintf(format, args...), "\n")
+	j.writeLine(j.Stdout, j.logName(), msg)
+}
+
+// logName returns the log file for the current phase of the job
+func (j *job) logName() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return logName(j.Phase)
+}
+
+// writeLine writes a line of output to the tail, the
+// log file name, and to out prefixed with the job name
+func (j *job) writeLine(out io.Writer, name, line string) {
+	j.tail.add(line)
+	err := j.logs.write(name, line)
+	if err != nil {
+		fmt.Printf("[%s]: %v\n", j.Name, err)
+	}
+	if out == nil {
+		out = os.Stdout
+	}
+	_, err = fmt.Fprintf(out, "[%s]: %s\n", j.Name, line)
+	if err != nil {
+		fmt.Printf("failed to Fprintf: %v\n", err)
+	}
+}
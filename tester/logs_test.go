@@ -0,0 +1,127 @@
+package tester
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogName(t *testing.T) {
+	tt := map[string]struct {
+		phase    string
+		expected string
+	}{
+		"emulator": {phase: phaseEmulator, expected: emulatorLog},
+		"init":     {phase: phaseInit, expected: terraformLog},
+		"apply":    {phase: phaseApply, expected: terraformLog},
+		"test":     {phase: phaseTest, expected: testLog},
+		"destroy":  {phase: phaseDestroy, expected: terraformLog},
+		"hooks":    {phase: phaseHooks, expected: hooksLog},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual := logName(tc.phase)
+			if actual != tc.expected {
+				t.Errorf("log name invalid, expected: %s, got: %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJobLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	stdout := &bytes.Buffer{}
+	j := &job{Name: "bucket[small]", tail: &tailBuffer{}}
+	j.setLogs(&Config{ArtifactsDir: dir, stdout: stdout, stderr: stdout})
+
+	expectedDir := filepath.Join(dir, "bucket_small_")
+	if j.LogDir != expectedDir {
+		t.Fatalf("log dir invalid, expected: %s, got: %s", expectedDir, j.LogDir)
+	}
+
+	j.setPhase(phaseEmulator)
+	j.printf("starting %s\n", "emulator")
+	j.setPhase(phaseTest)
+	j.printf("running test")
+
+	err = j.logs.close()
+	if err != nil {
+		t.Fatalf("failed to close logs: %v", err)
+	}
+	// writes after close are dropped
+	j.printf("ignored")
+
+	files := map[string]string{
+		emulatorLog: "starting emulator\n",
+		testLog:     "running test\n",
+	}
+	for name, expected := range files {
+		data, err := ioutil.ReadFile(filepath.Join(expectedDir, name))
+		if err != nil {
+			t.Fatalf("failed to read log file: %s -> %v", name, err)
+		}
+		if string(data) != expected {
+			t.Errorf("log file invalid: %s, expected: %q, got: %q", name, expected, string(data))
+		}
+	}
+
+	if !strings.Contains(stdout.String(), "[bucket[small]]: running test\n") {
+		t.Errorf("output invalid, got: %q", stdout.String())
+	}
+	if !strings.HasSuffix(j.tail.String(), "ignored") {
+		t.Errorf("tail invalid, got: %q", j.tail.String())
+	}
+}
+
+func TestPrepareArtifacts(t *testing.T) {
+	base, err := ioutil.TempDir("", "workspaces")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(base)
+
+	cfg := &Config{WorkspaceDir: base}
+	err = prepareArtifacts(cfg)
+	if err != nil {
+		t.Fatalf("failed to prepare artifacts: %v", err)
+	}
+	if !cfg.removeArtifacts || filepath.Dir(cfg.ArtifactsDir) != base {
+		t.Errorf("temporary artifacts dir invalid, got: %s", cfg.ArtifactsDir)
+	}
+
+	cfg = &Config{ArtifactsDir: "artifacts"}
+	err = prepareArtifacts(cfg)
+	if err != nil {
+		t.Fatalf("failed to prepare artifacts: %v", err)
+	}
+	if cfg.removeArtifacts || !filepath.IsAbs(cfg.ArtifactsDir) {
+		t.Errorf("artifacts dir invalid, got: %s", cfg.ArtifactsDir)
+	}
+	if len(cfg.CoverageFile) != 0 {
+		t.Errorf("coverage file set without Coverage, got: %s", cfg.CoverageFile)
+	}
+
+	// the merged coverage profile must outlive the run
+	cfg = &Config{WorkspaceDir: base, Coverage: true}
+	err = prepareArtifacts(cfg)
+	if err != nil {
+		t.Fatalf("failed to prepare artifacts: %v", err)
+	}
+	expected := filepath.Join(cfg.ArtifactsDir, coverageFile)
+	if cfg.CoverageFile != expected {
+		t.Errorf("coverage file invalid, expected: %s, got: %s", expected, cfg.CoverageFile)
+	}
+	if cfg.removeArtifacts {
+		t.Error("artifacts dir containing the coverage file must be kept")
+	}
+}
@@ -0,0 +1,145 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MatrixEntry is a single combination of Terraform input variables,
+// each entry of JobConfig.Matrix is run as its own job
+type MatrixEntry struct {
+	// Name is appended to the job name, for example: cloudtrail[Name].
+	// If it is not set, it will default to the sorted list of Vars
+	// followed by the VarFiles
+	Name string `json:"name"`
+
+	// Vars are passed to terraform using -var, string values are
+	// passed as-is and all other values are JSON encoded
+	Vars map[string]interface{} `json:"vars"`
+
+	// VarFiles are passed to terraform using -var-file, the paths
+	// are relative to the job directory
+	VarFiles []string `json:"var_files"`
+}
+
+// args returns the -var and -var-file arguments for the entry,
+// relative var files are resolved against dir
+func (m *MatrixEntry) args(dir string) ([]string, error) {
+	vars, err := m.vars()
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, len(vars)+len(m.VarFiles))
+	for _, v := range vars {
+		args = append(args, "-var", v)
+	}
+	for _, f := range m.VarFiles {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(dir, f)
+		}
+		args = append(args, "-var-file="+f)
+	}
+	return args, nil
+}
+
+// vars returns the sorted name=value pairs of Vars
+func (m *MatrixEntry) vars() ([]string, error) {
+	names := make([]string, 0, len(m.Vars))
+	for name := range m.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vars := make([]string, 0, len(names))
+	for _, name := range names {
+		value := m.Vars[name]
+		if s, ok := value.(string); ok {
+			vars = append(vars, name+"="+s)
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode variable: %s -> %v", name, err)
+		}
+		vars = append(vars, name+"="+string(data))
+	}
+	return vars, nil
+}
+
+// label returns the Name of the entry or one built from its values
+func (m *MatrixEntry) label() (string, error) {
+	if len(m.Name) > 0 {
+		return m.Name, nil
+	}
+	vars, err := m.vars()
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(append(vars, m.VarFiles...), ","), nil
+}
+
+// autoLoaded matches the tfvars files that terraform always loads
+var autoLoaded = regexp.MustCompile(`^terraform\.tfvars$|\.auto\.tfvars$`)
+
+// discoverMatrix returns an entry for each *.tfvars file inside of dir
+// excluding the files that terraform loads automatically
+func discoverMatrix(dir string) ([]MatrixEntry, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tfvars"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tfvars files in: %q -> %v", dir, err)
+	}
+	sort.Strings(matches)
+
+	var entries []MatrixEntry
+	for _, m := range matches {
+		name := filepath.Base(m)
+		if autoLoaded.MatchString(name) {
+			continue
+		}
+		entries = append(entries, MatrixEntry{
+			Name:     strings.TrimSuffix(name, ".tfvars"),
+			VarFiles: []string{name},
+		})
+	}
+	return entries, nil
+}
+
+// expandMatrix returns a job for each entry of the job's matrix,
+// declared in its JobConfig or discovered from *.tfvars files, or
+// the job itself when there is no matrix
+func expandMatrix(j *job, env []string) ([]*job, error) {
+	entries := j.Config.Matrix
+	if len(entries) == 0 {
+		var err error
+		entries, err = discoverMatrix(j.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(entries) == 0 {
+		return []*job{j}, nil
+	}
+
+	jobs := make([]*job, 0, len(entries))
+	for i := range entries {
+		entry := entries[i]
+		label, err := entry.label()
+		if err != nil {
+			return nil, err
+		}
+		args, err := entry.args(j.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		name := fmt.Sprintf("%s[%s]", j.Name, label)
+		mj := newJob(name, j.RootPath, j.Path, j.TestFile, env, j.Config)
+		mj.VarArgs = args
+		jobs = append(jobs, mj)
+	}
+	return jobs, nil
+}
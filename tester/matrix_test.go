@@ -0,0 +1,91 @@
+package tester
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandMatrix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "matrix")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, f := range []string{"terraform.tfvars", "x.auto.tfvars", "kms.tfvars", "no_kms.tfvars"} {
+		err = ioutil.WriteFile(filepath.Join(dir, f), nil, 0600)
+		if err != nil {
+			t.Fatalf("failed to write file: %s -> %v", f, err)
+		}
+	}
+
+	tt := map[string]struct {
+		matrix       []MatrixEntry
+		expectedName []string
+		expectedArgs [][]string
+	}{
+		"declared": {
+			matrix: []MatrixEntry{
+				{Vars: map[string]interface{}{"multi_region": true, "name": "trail"}},
+				{Name: "single", VarFiles: []string{"single.tfvars"}},
+			},
+			expectedName: []string{"cloudtrail[multi_region=true,name=trail]", "cloudtrail[single]"},
+			expectedArgs: [][]string{
+				{"-var", "multi_region=true", "-var", "name=trail"},
+				{"-var-file=" + filepath.Join(dir, "single.tfvars")},
+			},
+		},
+		"discovered": {
+			expectedName: []string{"cloudtrail[kms]", "cloudtrail[no_kms]"},
+			expectedArgs: [][]string{
+				{"-var-file=" + filepath.Join(dir, "kms.tfvars")},
+				{"-var-file=" + filepath.Join(dir, "no_kms.tfvars")},
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			j := newJob("cloudtrail", dir, dir, "a_test.go", nil, &JobConfig{Matrix: tc.matrix})
+			jobs, err := expandMatrix(j, nil)
+			if err != nil {
+				t.Fatalf("failed to expand matrix: %v", err)
+			}
+			var (
+				names []string
+				args  [][]string
+			)
+			for _, mj := range jobs {
+				names = append(names, mj.Name)
+				args = append(args, mj.VarArgs)
+			}
+			if !reflect.DeepEqual(names, tc.expectedName) {
+				t.Errorf("names invalid, expected: %v, got: %v", tc.expectedName, names)
+			}
+			if !reflect.DeepEqual(args, tc.expectedArgs) {
+				t.Errorf("args invalid, expected: %v, got: %v", tc.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestExpandMatrixWithoutEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "matrix")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := newJob("bucket", dir, dir, "a_test.go", nil, &JobConfig{})
+	jobs, err := expandMatrix(j, nil)
+	if err != nil {
+		t.Fatalf("failed to expand matrix: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0] != j {
+		t.Errorf("expected the job itself, got: %v", jobs)
+	}
+}
@@ -0,0 +1,144 @@
+// Package output provides typed access to the Terraform outputs
+// that tester exposes to each job's test process
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// FileEnv is the ENV variable containing the path to the JSON
+// file holding the `terraform output -json` of the job
+const FileEnv = "TF_OUTPUTS_FILE"
+
+// EnvPrefix is prepended to the name of each Terraform output to
+// form the ENV variable holding its value, string values are
+// provided as-is and all other values are JSON encoded
+const EnvPrefix = "TF_OUTPUT_"
+
+// Outputs contains the raw JSON value of each Terraform output
+type Outputs map[string]json.RawMessage
+
+// Read returns the Terraform outputs from the file referenced by
+// TF_OUTPUTS_FILE, if it is not set, outputs are read from the
+// TF_OUTPUT_<name> ENV variables instead
+func Read() (Outputs, error) {
+	path := os.Getenv(FileEnv)
+	if len(path) == 0 {
+		return fromEnv(os.Environ()), nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outputs file: %q -> %v", path, err)
+	}
+
+	var raw map[string]struct {
+		Value json.RawMessage `json:"value"`
+	}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse outputs file: %q -> %v", path, err)
+	}
+
+	outputs := make(Outputs, len(raw))
+	for name, o := range raw {
+		outputs[name] = o.Value
+	}
+	return outputs, nil
+}
+
+// Load calls Read and fails the test if the outputs cannot be read
+func Load(t *testing.T) Outputs {
+	outputs, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return outputs
+}
+
+func fromEnv(env []string) Outputs {
+	outputs := make(Outputs)
+	for _, e := range env {
+		if !strings.HasPrefix(e, EnvPrefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(e, EnvPrefix), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := []byte(parts[1])
+		// string values are not JSON encoded
+		if !json.Valid(value) {
+			value, _ = json.Marshal(parts[1])
+		}
+		outputs[parts[0]] = value
+	}
+	return outputs
+}
+
+// Decode unmarshals the value of the named output into v
+// and fails the test if it is missing or cannot be decoded
+func (o Outputs) Decode(t *testing.T, name string, v interface{}) {
+	value, ok := o[name]
+	if !ok {
+		t.Fatalf("terraform output not found: %s", name)
+	}
+	err := json.Unmarshal(value, v)
+	if err != nil {
+		t.Fatalf("failed to decode terraform output: %s -> %v", name, err)
+	}
+}
+
+// String returns the value of the named output as a string, non-string
+// values are returned as they were encoded in JSON
+func (o Outputs) String(t *testing.T, name string) string {
+	value, ok := o[name]
+	if !ok {
+		t.Fatalf("terraform output not found: %s", name)
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return string(value)
+	}
+	return s
+}
+
+// Int returns the value of the named output as an int
+func (o Outputs) Int(t *testing.T, name string) int {
+	var i int
+	o.Decode(t, name, &i)
+	return i
+}
+
+// Float returns the value of the named output as a float64
+func (o Outputs) Float(t *testing.T, name string) float64 {
+	var f float64
+	o.Decode(t, name, &f)
+	return f
+}
+
+// Bool returns the value of the named output as a bool
+func (o Outputs) Bool(t *testing.T, name string) bool {
+	var b bool
+	o.Decode(t, name, &b)
+	return b
+}
+
+// StringSlice returns the value of the named output as a []string
+func (o Outputs) StringSlice(t *testing.T, name string) []string {
+	var s []string
+	o.Decode(t, name, &s)
+	return s
+}
+
+// StringMap returns the value of the named output as a map[string]string
+func (o Outputs) StringMap(t *testing.T, name string) map[string]string {
+	var m map[string]string
+	o.Decode(t, name, &m)
+	return m
+}
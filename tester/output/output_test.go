@@ -0,0 +1,83 @@
+package output
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+var outputsJSON = []byte(`{
+	"name": {"sensitive": false, "type": "string", "value": "alias/key"},
+	"count": {"sensitive": false, "type": "number", "value": 3},
+	"enabled": {"sensitive": false, "type": "bool", "value": true},
+	"ids": {"sensitive": false, "type": ["list", "string"], "value": ["a", "b"]},
+	"tags": {"sensitive": false, "type": ["map", "string"], "value": {"a": "b"}}
+}`)
+
+func TestRead(t *testing.T) {
+	f, err := ioutil.TempFile("", "outputs")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(outputsJSON)
+	if err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("failed to close temp file: %v", err)
+	}
+
+	err = os.Setenv(FileEnv, f.Name())
+	if err != nil {
+		t.Fatalf("failed to set %s: %v", FileEnv, err)
+	}
+	defer os.Unsetenv(FileEnv)
+
+	o := Load(t)
+	if v := o.String(t, "name"); v != "alias/key" {
+		t.Errorf("name invalid, expected: alias/key, got: %s", v)
+	}
+	if v := o.Int(t, "count"); v != 3 {
+		t.Errorf("count invalid, expected: 3, got: %d", v)
+	}
+	if v := o.Float(t, "count"); v != 3 {
+		t.Errorf("count invalid, expected: 3, got: %f", v)
+	}
+	if v := o.Bool(t, "enabled"); !v {
+		t.Errorf("enabled invalid, expected: true, got: %t", v)
+	}
+	if v := o.StringSlice(t, "ids"); !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Errorf("ids invalid, expected: [a b], got: %v", v)
+	}
+	if v := o.StringMap(t, "tags"); !reflect.DeepEqual(v, map[string]string{"a": "b"}) {
+		t.Errorf("tags invalid, expected: map[a:b], got: %v", v)
+	}
+	if v := o.String(t, "count"); v != "3" {
+		t.Errorf("count invalid, expected: 3, got: %s", v)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	o := fromEnv([]string{
+		"PATH=/bin",
+		EnvPrefix + "name=alias/key",
+		EnvPrefix + "count=3",
+		EnvPrefix + "ids=[\"a\",\"b\"]",
+	})
+	if len(o) != 3 {
+		t.Fatalf("outputs invalid, expected 3 outputs, got: %v", o)
+	}
+	if v := o.String(t, "name"); v != "alias/key" {
+		t.Errorf("name invalid, expected: alias/key, got: %s", v)
+	}
+	if v := o.Int(t, "count"); v != 3 {
+		t.Errorf("count invalid, expected: 3, got: %d", v)
+	}
+	if v := o.StringSlice(t, "ids"); !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Errorf("ids invalid, expected: [a b], got: %v", v)
+	}
+}
@@ -0,0 +1,115 @@
+package tester
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+
+	tfoutput "github.com/GSA/grace-tftest/tester/output"
+)
+
+// Output is a single value from the outputs of a Driver
+type Output struct {
+	Sensitive bool            `json:"sensitive"`
+	Type      json.RawMessage `json:"type"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// injectOutputs writes the Terraform outputs to the job's outputs
+// file and exposes them to every process started afterwards
+func (j *job) injectOutputs(ctx context.Context) error {
+	outputs, err := j.readOutputs(ctx, j.dir())
+	if err != nil {
+		return err
+	}
+	return j.setOutputs(outputs)
+}
+
+// setOutputs writes outputs to the job's outputs file and
+// exposes them to every process started afterwards
+func (j *job) setOutputs(outputs map[string]Output) error {
+	// the outputs are written in the format of terraform
+	// output -json no matter which driver is in use
+	data, err := json.Marshal(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to encode outputs: %v", err)
+	}
+	err = ioutil.WriteFile(j.OutputsFile, data, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write outputs at: %q -> %v", j.OutputsFile, err)
+	}
+
+	j.mu.Lock()
+	j.Env = append(j.Env, tfoutput.FileEnv+"="+j.OutputsFile)
+	j.Env = append(j.Env, outputsEnv(outputs)...)
+	j.mu.Unlock()
+	return nil
+}
+
+// readOutputs returns the outputs of the Terraform applied inside of dir
+func (j *job) readOutputs(ctx context.Context, dir string) (map[string]Output, error) {
+	data, err := j.captureStep(ctx, dir, StepOutput)
+	if err != nil {
+		return nil, err
+	}
+	return j.driver().ParseOutputs(data)
+}
+
+// outputsEnv returns a KEY=VALUE slice for the provided outputs
+func outputsEnv(outputs map[string]Output) []string {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make([]string, 0, len(outputs))
+	for _, name := range names {
+		env = append(env, tfoutput.EnvPrefix+name+"="+outputValue(outputs[name].Value))
+	}
+	return env
+}
+
+// outputValue returns strings unquoted so they are usable
+// without decoding them first, other values remain JSON
+func outputValue(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value)
+}
+
+// captureProcess runs the process to completion inside of dir
+// and returns its stdout, stderr is included in any error
+func (j *job) captureProcess(ctx context.Context, dir, path string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(path, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	cmd.Env = append(cmd.Env, j.Env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	setProcessGroup(cmd)
+
+	err := cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start process: %s -> %v", path, err)
+	}
+
+	p := &Process{Cmd: cmd, done: make(chan struct{})}
+	go watchContext(ctx, p)
+
+	err = cmd.Wait()
+	close(p.done)
+	if err != nil {
+		return nil, fmt.Errorf("%s -> %v: %s", path, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,24 @@
+package tester
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOutputsEnv(t *testing.T) {
+	outputs := map[string]Output{
+		"name":  {Value: json.RawMessage(`"alias/key"`)},
+		"count": {Value: json.RawMessage(`3`)},
+		"ids":   {Value: json.RawMessage(`["a","b"]`)},
+	}
+	expected := []string{
+		"TF_OUTPUT_count=3",
+		`TF_OUTPUT_ids=["a","b"]`,
+		"TF_OUTPUT_name=alias/key",
+	}
+	actual := outputsEnv(outputs)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("env invalid, expected: %v, got: %v", expected, actual)
+	}
+}
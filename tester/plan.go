@@ -0,0 +1,109 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/GSA/grace-tftest/tfplan"
+)
+
+// unreachableEndpoint is used for every custom endpoint in plan-only
+// mode, so nothing that the plan requests is able to reach AWS
+const unreachableEndpoint = "http://127.0.0.1:1"
+
+// planFile is the name of the plan written to the workspace in plan-only mode
+const planFile = "terraform.tfplan"
+
+// planOnly returns true if the job stops after terraform plan
+func (j *job) planOnly(cfg *Config) bool {
+	if j.Config != nil && j.Config.PlanOnly {
+		return true
+	}
+	return cfg.PlanOnly
+}
+
+// runPlanOnly initializes and plans the job without an emulator,
+// the JSON plan is written to PlanFile for the test to assert against.
+// The plan takes the place of apply for the BeforeApply and AfterApply
+// hooks, the emulator hooks are never run
+func (j *job) runPlanOnly(ctx context.Context, cfg *Config) error {
+	if len(j.Fixtures) > 0 {
+		return errors.New("dependencies and fixtures cannot be applied in plan-only mode")
+	}
+
+	services := cfg.Services
+	if len(services) == 0 {
+		services = []string{"sts"}
+	}
+	err := writeProvider(j.ProviderFile, j.provider(cfg), services, unreachableEndpoint, filepath.Base(j.StateFile))
+	if err != nil {
+		return err
+	}
+
+	err = j.runPhase(ctx, phaseInit, cfg.Timeouts.Init, j.runInit)
+	if err != nil {
+		return err
+	}
+
+	j.Services, err = j.resolveServices(cfg, j.dir())
+	if err != nil {
+		return err
+	}
+	err = writeProvider(j.ProviderFile, j.provider(cfg), j.Services, unreachableEndpoint, filepath.Base(j.StateFile))
+	if err != nil {
+		return err
+	}
+
+	err = j.runHooks(ctx, cfg, BeforeApply, nil)
+	if err != nil {
+		return err
+	}
+
+	err = j.runPhase(ctx, phasePlan, cfg.Timeouts.Plan, j.writePlan)
+	if err != nil {
+		return err
+	}
+
+	err = j.runHooks(ctx, cfg, AfterApply, nil)
+	if err != nil {
+		return err
+	}
+
+	err = j.runHooks(ctx, cfg, BeforeTest, nil)
+	if err != nil {
+		return err
+	}
+
+	err = j.runPhase(ctx, phaseTest, cfg.Timeouts.Test, j.runTest)
+	if err != nil {
+		return err
+	}
+	return j.runHooks(ctx, cfg, AfterTest, nil)
+}
+
+// writePlan runs terraform plan and writes the output of
+// `terraform show -json` for the plan to PlanFile
+func (j *job) writePlan(ctx context.Context) error {
+	plan := filepath.Join(j.dir(), planFile)
+	err := j.runStep(ctx, j.dir(), StepPlan, append([]string{"-out=" + plan}, j.VarArgs...)...)
+	if err != nil {
+		return err
+	}
+
+	data, err := j.captureStep(ctx, j.dir(), StepShow, plan)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(j.PlanFile, data, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write plan at: %q -> %v", j.PlanFile, err)
+	}
+
+	j.mu.Lock()
+	j.Env = append(j.Env, tfplan.FileEnv+"="+j.PlanFile)
+	j.mu.Unlock()
+	return nil
+}
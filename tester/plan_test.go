@@ -0,0 +1,95 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanOnly(t *testing.T) {
+	tt := map[string]struct {
+		cfg      *Config
+		jc       *JobConfig
+		expected bool
+	}{
+		"default": {cfg: &Config{}, jc: &JobConfig{}},
+		"config":  {cfg: &Config{PlanOnly: true}, jc: &JobConfig{}, expected: true},
+		"job":     {cfg: &Config{}, jc: &JobConfig{PlanOnly: true}, expected: true},
+		"nil_job": {cfg: &Config{PlanOnly: true}, expected: true},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual := (&job{Config: tc.jc}).planOnly(tc.cfg)
+			if actual != tc.expected {
+				t.Errorf("plan only invalid, expected: %t, got: %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRunPlanOnlyFixtures(t *testing.T) {
+	j := &job{Name: "fixtures", Fixtures: []fixture{{Name: "bucket"}}}
+	err := j.runPlanOnly(context.Background(), &Config{})
+	if err == nil {
+		t.Errorf("expected an error for fixtures in plan-only mode")
+	}
+}
+
+// versionDriver runs go version for every step so the steps of a
+// job are able to run without terraform
+type versionDriver struct {
+	Terraform
+}
+
+func (d *versionDriver) Command(Step, ...string) (string, []string) {
+	return "go", []string{"version"}
+}
+
+func TestRunPlanOnlyHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := newJob("hooks", dir, dir, "", nil, &JobConfig{})
+	j.Stdout, j.Stderr = ioutil.Discard, ioutil.Discard
+	j.tool = &versionDriver{}
+	err = j.prepareWorkspace("")
+	if err != nil {
+		t.Fatalf("failed to prepare workspace: %v", err)
+	}
+	defer j.removeWorkspace(false)
+
+	// the test is not run, so the hooks stop the job before it
+	var points []HookPoint
+	record := func(ctx context.Context, hj *HookJob) error {
+		points = append(points, hj.Point)
+		if hj.Point == BeforeTest {
+			return errors.New("stop")
+		}
+		return nil
+	}
+	cfg := &Config{Services: []string{"s3"}}
+	for _, point := range []HookPoint{BeforeEmulator, AfterEmulator, BeforeApply, AfterApply, BeforeTest} {
+		cfg.Hooks = append(cfg.Hooks, Hook{Point: point, Func: record})
+	}
+
+	err = j.runPlanOnly(context.Background(), cfg)
+	if err == nil || !strings.Contains(err.Error(), "stop") {
+		t.Fatalf("error invalid, expected: stop, got: %v", err)
+	}
+	expected := []HookPoint{BeforeApply, AfterApply, BeforeTest}
+	if !reflect.DeepEqual(points, expected) {
+		t.Errorf("hook points invalid, expected: %v, got: %v", expected, points)
+	}
+	if _, err := os.Stat(j.PlanFile); err != nil {
+		t.Errorf("plan was not written: %v", err)
+	}
+}
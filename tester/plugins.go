@@ -0,0 +1,145 @@
+package tester
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// cliConfigTemplate is the terraform CLI configuration used when a
+// provider mirror is configured, providers are only installed from
+// the mirror so no network access is required
+var cliConfigTemplate = template.Must(template.New("cli").Parse(`plugin_cache_dir = "{{.CacheDir}}"
+
+provider_installation {
+	filesystem_mirror {
+		path    = "{{.Mirror}}"
+		include = ["*/*"]
+	}
+}
+`))
+
+// defaultPluginCacheDir returns tftest/plugins inside of the user
+// cache directory or the system temporary directory
+func defaultPluginCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "tftest", "plugins")
+}
+
+// preparePlugins creates the shared plugin cache and the CLI
+// configuration for the provider mirror, then prepends their
+// ENV variables so Config.Env is still able to override them
+func preparePlugins(cfg *Config) error {
+	cacheDir, err := filepath.Abs(cfg.PluginCacheDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve absolute path to %s -> %v", cfg.PluginCacheDir, err)
+	}
+	err = os.MkdirAll(cacheDir, 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create plugin cache: %q -> %v", cacheDir, err)
+	}
+	// since terraform 1.4 the cache is only used for providers that
+	// are already in the lock file, which the workspaces do not have
+	vars := []string{
+		"TF_PLUGIN_CACHE_DIR=" + cacheDir,
+		"TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE=true",
+	}
+
+	if len(cfg.ProviderMirror) > 0 {
+		path, err := writeCLIConfig(cacheDir, cfg.ProviderMirror)
+		if err != nil {
+			return err
+		}
+		cfg.cliConfig = path
+		vars = append(vars, "TF_CLI_CONFIG_FILE="+path)
+	}
+
+	cfg.vars = append(vars, cfg.vars...)
+	return nil
+}
+
+// writeCLIConfig writes a temporary CLI configuration file
+// that installs providers from the mirror directory
+func writeCLIConfig(cacheDir, mirror string) (string, error) {
+	mirror, err := filepath.Abs(mirror)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve absolute path to %s -> %v", mirror, err)
+	}
+
+	var buf bytes.Buffer
+	err = cliConfigTemplate.Execute(&buf, struct {
+		CacheDir string
+		Mirror   string
+	}{
+		CacheDir: filepath.ToSlash(cacheDir),
+		Mirror:   filepath.ToSlash(mirror),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to execute cli config template: %v", err)
+	}
+
+	f, err := ioutil.TempFile("", "tftest-*.tfrc")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cli config: %v", err)
+	}
+	_, err = f.Write(buf.Bytes())
+	if err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to write cli config: %q -> %v", f.Name(), err)
+	}
+	err = f.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to close cli config: %q -> %v", f.Name(), err)
+	}
+	return f.Name(), nil
+}
+
+// warmPluginCache initializes a workspace containing only the generated
+// provider configuration so the providers are in the cache before
+// any job runs terraform init, preventing the jobs from racing to
+// populate the cache
+func warmPluginCache(cfg *Config) error {
+	dir, err := ioutil.TempDir(cfg.WorkspaceDir, "tftest-plugins-")
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := newJob(internalPrefix+"plugins", dir, dir, "", cfg.vars, &JobConfig{})
+	j.Phase = phaseInit
+	j.setLogs(cfg)
+	defer func() {
+		err := j.logs.close()
+		if err != nil {
+			fmt.Printf("failed to close plugin cache logs: %v\n", err)
+		}
+	}()
+	err = writeProvider(filepath.Join(dir, "provider.tf"), cfg.Provider, cfg.Services, "http://localhost", "terraform.tfstate")
+	if err != nil {
+		return err
+	}
+
+	// terragrunt refuses to run without a configuration file,
+	// an empty one runs the wrapped tool in the directory itself
+	if _, ok := cfg.Driver.(*Terragrunt); ok {
+		err = ioutil.WriteFile(filepath.Join(dir, "terragrunt.hcl"), nil, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to write terragrunt config: %v", err)
+		}
+	}
+
+	j.tool = cfg.Driver
+	j.printf("warming the plugin cache...\n")
+	err = j.runStep(context.Background(), dir, StepInit, "-backend=false")
+	if err != nil {
+		return fmt.Errorf("failed to warm plugin cache: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package tester
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreparePlugins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := &Config{
+		PluginCacheDir: filepath.Join(dir, "cache"),
+		ProviderMirror: filepath.Join(dir, "mirror"),
+		vars:           []string{"TF_PLUGIN_CACHE_DIR=override"},
+	}
+	err = preparePlugins(cfg)
+	if err != nil {
+		t.Fatalf("failed to prepare plugins: %v", err)
+	}
+	defer os.Remove(cfg.cliConfig)
+
+	if _, err := os.Stat(cfg.PluginCacheDir); err != nil {
+		t.Errorf("plugin cache was not created: %v", err)
+	}
+
+	// variables from Config.Env must still take precedence
+	if v := envValue(cfg.vars, "TF_PLUGIN_CACHE_DIR"); v != "override" {
+		t.Errorf("TF_PLUGIN_CACHE_DIR invalid, expected: override, got: %s", v)
+	}
+	if v := envValue(cfg.vars, "TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE"); v != "true" {
+		t.Errorf("TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE invalid, expected: true, got: %s", v)
+	}
+	if v := envValue(cfg.vars, "TF_CLI_CONFIG_FILE"); v != cfg.cliConfig {
+		t.Errorf("TF_CLI_CONFIG_FILE invalid, expected: %s, got: %s", cfg.cliConfig, v)
+	}
+
+	data, err := ioutil.ReadFile(cfg.cliConfig)
+	if err != nil {
+		t.Fatalf("failed to read cli config: %v", err)
+	}
+	for _, expected := range []string{
+		`plugin_cache_dir = "` + filepath.ToSlash(cfg.PluginCacheDir) + `"`,
+		`path    = "` + filepath.ToSlash(cfg.ProviderMirror) + `"`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("cli config is missing: %s, got: %s", expected, data)
+		}
+	}
+}
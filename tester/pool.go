@@ -0,0 +1,207 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Resetter is implemented by emulators that are able to clear
+// all of their state, only emulators implementing Resetter can
+// be shared between jobs using Config.PoolSize
+type Resetter interface {
+	Reset() error
+}
+
+// motoResetPath is the moto endpoint that clears all backends
+const motoResetPath = "/moto-api/reset"
+
+// Reset clears the state of every moto backend
+func (m *Moto) Reset() error {
+	if m.bin == nil {
+		return errors.New("moto_server has not been started")
+	}
+	return resetURL(m.Endpoint() + motoResetPath)
+}
+
+// resetURL sends an empty POST to rawurl and
+// expects a successful status code in return
+func resetURL(rawurl string) error {
+	//nolint: gosec
+	resp, err := http.Post(rawurl, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("failed to reset emulator: %s -> %v", rawurl, err)
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close response body: %v", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("failed to reset emulator: %s -> %s", rawurl, resp.Status)
+	}
+	return nil
+}
+
+// pooledEmulator is an emulator owned by the pool, owner is used
+// to start its processes and to keep up with them, processes are
+// those still running once the emulator was ready
+type pooledEmulator struct {
+	Emulator
+	index     int
+	owner     *job
+	processes []*Process
+	err       error
+}
+
+// healthy returns an error if the emulator failed
+// to start or any of its processes have exited
+func (m *pooledEmulator) healthy() error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, p := range m.processes {
+		if p.Exited() {
+			return fmt.Errorf("emulator process exited: %s", p.Path)
+		}
+	}
+	return nil
+}
+
+func (m *pooledEmulator) stop() {
+	err := m.Stop()
+	if err != nil {
+		m.owner.printf("failed to stop emulator: %v\n", err)
+	}
+	m.owner.cleanupProcesses()
+	err = m.owner.logs.close()
+	if err != nil {
+		fmt.Printf("[%s]: %v\n", m.owner.Name, err)
+	}
+}
+
+// pool is a fixed set of emulators that are started once
+// and handed out to jobs as they run
+type pool struct {
+	cfg     *Config
+	factory EmulatorFactory
+	free    chan *pooledEmulator
+
+	mu      sync.Mutex
+	members []*pooledEmulator
+}
+
+// newPool starts size emulators in parallel, an emulator that fails
+// to start is kept in the pool and replaced when it is acquired
+func newPool(cfg *Config, size int) (*pool, error) {
+	if _, ok := cfg.Emulator().(Resetter); !ok {
+		return nil, errors.New("the configured emulator does not implement tester.Resetter and cannot be pooled")
+	}
+
+	p := &pool{
+		cfg:     cfg,
+		factory: cfg.Emulator,
+		free:    make(chan *pooledEmulator, size),
+		members: make([]*pooledEmulator, size),
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < size; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m := p.start(i)
+			if m.err != nil {
+				m.owner.printf("failed to start emulator: %v\n", m.err)
+			}
+			p.free <- m
+		}(i)
+	}
+	wg.Wait()
+
+	return p, nil
+}
+
+// start launches the emulator in slot i and waits until it is ready
+func (p *pool) start(i int) *pooledEmulator {
+	m := &pooledEmulator{
+		Emulator: p.factory(),
+		index:    i,
+		owner: &job{
+			Name:   fmt.Sprintf("%semulator-%d", internalPrefix, i),
+			Path:   p.cfg.Dir,
+			Env:    p.cfg.vars,
+			Phase:  phaseEmulator,
+			Stdout: os.Stdout,
+			Stderr: os.Stderr,
+		},
+	}
+	m.owner.setLogs(p.cfg)
+
+	m.err = m.Start(m.owner.startProcess)
+	if m.err == nil {
+		m.err = m.Ready()
+	}
+	if m.err != nil {
+		m.stop()
+	} else {
+		// a process that lost the race for the port exits on its
+		// own while another one serves, so it must not count
+		// against the health of the emulator
+		m.processes = m.owner.runningProcesses(0)
+	}
+
+	p.mu.Lock()
+	p.members[i] = m
+	p.mu.Unlock()
+
+	return m
+}
+
+// acquire blocks until an emulator is free or ctx is done, then resets
+// its state, emulators that have crashed or fail to reset are replaced
+func (p *pool) acquire(ctx context.Context) (*pooledEmulator, error) {
+	var m *pooledEmulator
+	select {
+	case m = <-p.free:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to acquire emulator: %v", ctx.Err())
+	}
+
+	err := m.healthy()
+	if err == nil {
+		err = m.Emulator.(Resetter).Reset()
+	}
+	if err == nil {
+		return m, nil
+	}
+
+	m.owner.printf("replacing emulator: %v\n", err)
+	m.stop()
+	m = p.start(m.index)
+	if m.err != nil {
+		// return the failed emulator so the next
+		// caller attempts to replace it again
+		p.free <- m
+		return nil, fmt.Errorf("failed to replace emulator: %v", m.err)
+	}
+	return m, nil
+}
+
+// release returns the emulator to the pool
+func (p *pool) release(m *pooledEmulator) {
+	p.free <- m
+}
+
+// close stops every emulator in the pool
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range p.members {
+		if m != nil {
+			m.stop()
+		}
+	}
+}
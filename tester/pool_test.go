@@ -0,0 +1,156 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeEmulator struct {
+	started  *int32
+	resetErr error
+	resets   int
+}
+
+func (f *fakeEmulator) Start(StartFunc) error {
+	atomic.AddInt32(f.started, 1)
+	return nil
+}
+func (f *fakeEmulator) Ready() error     { return nil }
+func (f *fakeEmulator) Endpoint() string { return "http://localhost:5000" }
+func (f *fakeEmulator) Stop() error      { return nil }
+func (f *fakeEmulator) Reset() error {
+	f.resets++
+	return f.resetErr
+}
+
+func TestNewPoolRequiresResetter(t *testing.T) {
+	_, err := newPool(&Config{Emulator: NewExternal("http://localhost:5000")}, 1)
+	if err == nil {
+		t.Fatal("expected an error for an emulator that does not implement Resetter")
+	}
+}
+
+func TestPoolAcquire(t *testing.T) {
+	var started int32
+	cfg := &Config{Emulator: func() Emulator {
+		return &fakeEmulator{started: &started}
+	}}
+
+	p, err := newPool(cfg, 2)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer p.close()
+
+	if started != 2 {
+		t.Fatalf("started emulators invalid, expected: 2, got: %d", started)
+	}
+
+	m, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire emulator: %v", err)
+	}
+	if resets := m.Emulator.(*fakeEmulator).resets; resets != 1 {
+		t.Errorf("resets invalid, expected: 1, got: %d", resets)
+	}
+
+	// an emulator that fails to reset must be replaced
+	m.Emulator.(*fakeEmulator).resetErr = errors.New("crashed")
+	p.release(m)
+	_, err = p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire emulator: %v", err)
+	}
+	m2, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire emulator: %v", err)
+	}
+	if m2 == m || started != 3 {
+		t.Errorf("failed emulator was not replaced, started: %d", started)
+	}
+}
+
+func TestPoolAcquireCanceled(t *testing.T) {
+	var started int32
+	cfg := &Config{Emulator: func() Emulator {
+		return &fakeEmulator{started: &started}
+	}}
+
+	p, err := newPool(cfg, 1)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer p.close()
+
+	m, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire emulator: %v", err)
+	}
+
+	// the only emulator is in use so the wait ends with ctx
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = p.acquire(ctx)
+	if err == nil {
+		t.Fatal("expected an error once the context is done")
+	}
+
+	// the canceled wait must not have taken the emulator
+	p.release(m)
+	_, err = p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire emulator: %v", err)
+	}
+}
+
+// racingEmulator starts a process that exits at once, like one that
+// lost the race for the port, next to one that keeps serving
+type racingEmulator struct {
+	fakeEmulator
+	lost *Process
+}
+
+func (r *racingEmulator) Start(start StartFunc) error {
+	var err error
+	r.lost, err = start(os.Args[0], "-test.run=^$")
+	if err != nil {
+		return err
+	}
+	_, err = start(os.Args[0], "-test.run=TestHelperProcess")
+	return err
+}
+
+func (r *racingEmulator) Ready() error {
+	return r.lost.Wait()
+}
+
+func TestPoolHealthyIgnoresExitedProcesses(t *testing.T) {
+	var started int32
+	cfg := &Config{
+		vars: []string{helperEnv},
+		Emulator: func() Emulator {
+			return &racingEmulator{fakeEmulator: fakeEmulator{started: &started}}
+		},
+	}
+
+	p, err := newPool(cfg, 1)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer p.close()
+
+	m, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire emulator: %v", err)
+	}
+	if err := m.healthy(); err != nil {
+		t.Errorf("emulator must be healthy while a process serves: %v", err)
+	}
+	if len(m.processes) != 1 {
+		t.Errorf("processes invalid, expected: 1, got: %d", len(m.processes))
+	}
+}
@@ -0,0 +1,79 @@
+package tester
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// portAttempts is the number of times a free port is requested
+// before giving up, and the number of times an emulator is
+// restarted when its port has been taken by another process
+const portAttempts = 5
+
+// reserved contains the ports handed out to emulators that are still
+// running, the system is free to return a port again as soon as the
+// listener is closed so each one is tracked until it is released
+var reserved = struct {
+	sync.Mutex
+	ports map[int]bool
+}{ports: make(map[int]bool)}
+
+// getPort returns a free port that has not been
+// handed out to another running emulator
+func getPort() (int, error) {
+	for i := 0; i < portAttempts; i++ {
+		port, err := freePort()
+		if err != nil {
+			return 0, err
+		}
+
+		reserved.Lock()
+		if !reserved.ports[port] {
+			reserved.ports[port] = true
+			reserved.Unlock()
+			return port, nil
+		}
+		reserved.Unlock()
+	}
+	return 0, fmt.Errorf("failed to get an available port after %d attempts", portAttempts)
+}
+
+// releasePort allows the port to be handed out again
+func releasePort(port int) {
+	reserved.Lock()
+	delete(reserved.ports, port)
+	reserved.Unlock()
+}
+
+// freePort returns a port that the system considers free
+func freePort() (int, error) {
+	// open a connection on any free ephemeral port
+	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+
+	l, err := net.ListenTCP("tcp", addr)
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	// grab the port used to open that connection
+	// and return it to the caller
+	if addr, ok := l.Addr().(*net.TCPAddr); ok {
+		return addr.Port, nil
+	}
+
+	return 0, fmt.Errorf("failed to get an available port")
+}
+
+// addressInUse returns true if the output of an emulator
+// reports that its port was taken by another process
+func addressInUse(output string) bool {
+	output = strings.ToLower(output)
+	return strings.Contains(output, "address already in use") ||
+		strings.Contains(output, "port is already allocated")
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package tester
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the process in a new process group
+// so that it can be killed along with all of its children
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// kill is used to terminate a process and all of its sub-processes
+func kill(cmd *exec.Cmd) error {
+	// a negative pid signals every process in the group
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// shellCommand returns the shell and the arguments that run command
+func shellCommand(command string) (string, []string) {
+	return "sh", []string{"-c", command}
+}
@@ -0,0 +1,21 @@
+package tester
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// setProcessGroup does nothing on windows as TASKKILL
+// terminates the whole process tree
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// kill is used to terminate a process and all of its sub-processes
+func kill(cmd *exec.Cmd) error {
+	pid := strconv.Itoa(cmd.Process.Pid)
+	return exec.Command("TASKKILL", "/T", "/F", "/PID", pid).Run()
+}
+
+// shellCommand returns the shell and the arguments that run command
+func shellCommand(command string) (string, []string) {
+	return "cmd", []string{"/C", command}
+}
@@ -0,0 +1,197 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the status of a job shown by the progress view
+const (
+	statusPending = "PENDING"
+	statusRunning = "RUNNING"
+	statusPassed  = "PASSED"
+	statusFailed  = "FAILED"
+	statusFlaky   = "FLAKY"
+	statusSkipped = "SKIPPED"
+)
+
+// refreshInterval is how often the progress view is redrawn
+const refreshInterval = 500 * time.Millisecond
+
+// jobState is a snapshot of the progress of a job
+type jobState struct {
+	Name    string
+	Status  string
+	Phase   string
+	Elapsed time.Duration
+}
+
+func (s jobState) String() string {
+	line := fmt.Sprintf("%-20s%-10s", s.Name, s.Status)
+	if s.Status == statusPending || s.Status == statusSkipped {
+		return strings.TrimRight(line, " ")
+	}
+	if s.Status == statusRunning {
+		line += fmt.Sprintf("%-10s", s.Phase)
+	}
+	return line + s.Elapsed.Round(time.Second).String()
+}
+
+// begin records the time the job started
+func (j *job) begin() {
+	j.mu.Lock()
+	j.Started = time.Now()
+	j.mu.Unlock()
+}
+
+// finish records the result of the job
+func (j *job) finish(err error) {
+	j.mu.Lock()
+	j.Err = err
+	j.Finished = time.Now()
+	j.mu.Unlock()
+}
+
+// state returns the current progress of the job
+func (j *job) state(now time.Time) jobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	s := jobState{Name: j.Name, Phase: j.Phase}
+	switch {
+	case j.Skipped:
+		s.Status = statusSkipped
+	case j.Started.IsZero():
+		s.Status = statusPending
+	case j.Finished.IsZero():
+		s.Status = statusRunning
+		s.Elapsed = now.Sub(j.Started)
+	case j.Err != nil:
+		s.Status = statusFailed
+		s.Elapsed = j.Finished.Sub(j.Started)
+	case len(j.Attempts) > 0:
+		s.Status = statusFlaky
+		s.Elapsed = j.Finished.Sub(j.Started)
+	default:
+		s.Status = statusPassed
+		s.Elapsed = j.Finished.Sub(j.Started)
+	}
+	return s
+}
+
+// progress periodically prints the status of every job, on a terminal
+// the running jobs are redrawn in place below the finished jobs,
+// otherwise a line is printed each time the status or phase changes
+type progress struct {
+	out  io.Writer
+	jobs []*job
+	tty  bool
+
+	printed map[*job]string
+	lines   int
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newProgress(out io.Writer, tty bool, jobs []*job) *progress {
+	return &progress{
+		out:     out,
+		jobs:    jobs,
+		tty:     tty,
+		printed: make(map[*job]string),
+		stop:    make(chan struct{}),
+	}
+}
+
+// start redraws the progress view until close is called
+func (p *progress) start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			p.draw(time.Now())
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// close stops redrawing and prints the final status of every job
+func (p *progress) close() {
+	close(p.stop)
+	p.wg.Wait()
+	p.draw(time.Now())
+}
+
+// draw prints the jobs that have finished since the last draw, then
+// the running jobs and a summary when writing to a terminal
+func (p *progress) draw(now time.Time) {
+	var b strings.Builder
+	if p.lines > 0 {
+		// move to the start of the previous view and clear it
+		fmt.Fprintf(&b, "\x1b[%dA\x1b[J", p.lines)
+		p.lines = 0
+	}
+
+	counts := make(map[string]int)
+	var running []jobState
+	for _, j := range p.jobs {
+		s := j.state(now)
+		counts[s.Status]++
+
+		switch s.Status {
+		case statusPending:
+			continue
+		case statusRunning:
+			if p.tty {
+				running = append(running, s)
+				continue
+			}
+		}
+
+		// finished jobs are printed once and without
+		// a terminal, running jobs on each phase change
+		key := s.Status
+		if s.Status == statusRunning {
+			key += s.Phase
+		}
+		if p.printed[j] == key {
+			continue
+		}
+		p.printed[j] = key
+		fmt.Fprintln(&b, s)
+	}
+
+	if p.tty {
+		for _, s := range running {
+			fmt.Fprintln(&b, s)
+		}
+		fmt.Fprintf(&b, "%d running, %d pending, %d passed, %d failed, %d skipped\n",
+			counts[statusRunning], counts[statusPending], counts[statusPassed]+counts[statusFlaky], counts[statusFailed], counts[statusSkipped])
+		p.lines = len(running) + 1
+	}
+
+	_, err := io.WriteString(p.out, b.String())
+	if err != nil {
+		fmt.Printf("failed to write progress: %v\n", err)
+	}
+}
+
+// isTerminal returns true if f is a character device
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,101 @@
+package tester
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobState(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-90 * time.Second)
+
+	tt := map[string]struct {
+		j        *job
+		expected string
+	}{
+		"pending": {
+			j:        &job{Name: "pending"},
+			expected: "pending             PENDING",
+		},
+		"skipped": {
+			j:        &job{Name: "skipped", Skipped: true},
+			expected: "skipped             SKIPPED",
+		},
+		"running": {
+			j:        &job{Name: "running", Phase: phaseApply, Started: started},
+			expected: "running             RUNNING   apply     1m30s",
+		},
+		"passed": {
+			j:        &job{Name: "passed", Started: started, Finished: now.Add(-30 * time.Second)},
+			expected: "passed              PASSED    1m0s",
+		},
+		"failed": {
+			j:        &job{Name: "failed", Started: started, Finished: now, Err: errors.New("failed")},
+			expected: "failed              FAILED    1m30s",
+		},
+		"flaky": {
+			j:        &job{Name: "flaky", Started: started, Finished: now, Attempts: []error{errors.New("failed")}},
+			expected: "flaky               FLAKY     1m30s",
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual := tc.j.state(now).String()
+			if actual != tc.expected {
+				t.Errorf("state invalid, expected: %q, got: %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestProgressDraw(t *testing.T) {
+	now := time.Now()
+	running := &job{Name: "running", Phase: phaseInit, Started: now}
+	passed := &job{Name: "passed", Started: now, Finished: now}
+	jobs := []*job{running, passed, {Name: "pending"}}
+
+	tt := map[string]struct {
+		tty      bool
+		expected []string
+	}{
+		"terminal": {
+			tty: true,
+			expected: []string{
+				"passed              PASSED    0s\nrunning             RUNNING   init      0s\n1 running, 1 pending, 1 passed, 0 failed, 0 skipped\n",
+				"\x1b[2A\x1b[Jrunning             RUNNING   init      0s\n1 running, 1 pending, 1 passed, 0 failed, 0 skipped\n",
+				"\x1b[2A\x1b[Jrunning             RUNNING   apply     0s\n1 running, 1 pending, 1 passed, 0 failed, 0 skipped\n",
+			},
+		},
+		"plain": {
+			tty: false,
+			expected: []string{
+				"running             RUNNING   init      0s\npassed              PASSED    0s\n",
+				"",
+				"running             RUNNING   apply     0s\n",
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			running.setPhase(phaseInit)
+			out := &strings.Builder{}
+			p := newProgress(out, tc.tty, jobs)
+			for i, expected := range tc.expected {
+				if i == 2 {
+					running.setPhase(phaseApply)
+				}
+				out.Reset()
+				p.draw(now)
+				if out.String() != expected {
+					t.Errorf("draw %d invalid, expected: %q, got: %q", i, expected, out.String())
+				}
+			}
+		})
+	}
+}
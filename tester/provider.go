@@ -0,0 +1,159 @@
+package tester
+
+import (
+	"sort"
+	"strconv"
+	"text/template"
+)
+
+// ProviderConfig configures the provider.tf generated for each job
+type ProviderConfig struct {
+	// Version is the version constraint for hashicorp/aws added
+	// to required_providers, for example: ~> 3.0. If it is not
+	// set, required_providers is not generated
+	Version string `json:"version"`
+
+	// Region is the region of the default aws provider. If it is
+	// not set, the provider uses the AWS_REGION ENV variable
+	Region string `json:"region"`
+
+	// Aliases are additional aws providers, each one is pointed
+	// at the emulator using its own region
+	Aliases []ProviderAlias `json:"aliases"`
+
+	// DefaultTags are added to the default_tags block of every provider
+	DefaultTags map[string]string `json:"default_tags"`
+
+	// AssumeRoleARN adds an assume_role block using the role
+	// ARN to every provider
+	AssumeRoleARN string `json:"assume_role_arn"`
+
+	// UsePathStyle uses the s3_use_path_style argument introduced in
+	// version 4 of the provider instead of s3_force_path_style
+	UsePathStyle bool `json:"use_path_style"`
+
+	// Template replaces the default provider template, it is parsed
+	// using text/template and executed against a ProviderData
+	Template string `json:"template"`
+}
+
+// ProviderAlias is an aliased aws provider, for example: replica
+type ProviderAlias struct {
+	Alias  string `json:"alias"`
+	Region string `json:"region"`
+}
+
+// ProviderData is provided to the provider template
+type ProviderData struct {
+	// State is the path of the local backend state file
+	State string
+
+	// Endpoint is the URL of the emulator
+	Endpoint string
+
+	// Services are the custom endpoints pointed at the emulator
+	Services []string
+
+	// Version is the version constraint for hashicorp/aws
+	Version string
+
+	// Providers contains the default provider followed by the aliases
+	Providers []ProviderAlias
+
+	// DefaultTags are added to the default_tags block of every provider
+	DefaultTags map[string]string
+
+	// AssumeRoleARN is the role ARN of the assume_role block
+	AssumeRoleARN string
+
+	// PathStyleArgument is s3_force_path_style or s3_use_path_style
+	PathStyleArgument string
+}
+
+// newProviderData returns the template data for the provider config
+func newProviderData(pc *ProviderConfig, services []string, endpoint, state string) *ProviderData {
+	if pc == nil {
+		pc = &ProviderConfig{}
+	}
+
+	data := &ProviderData{
+		State:             state,
+		Endpoint:          endpoint,
+		Services:          services,
+		Version:           pc.Version,
+		Providers:         append([]ProviderAlias{{Region: pc.Region}}, pc.Aliases...),
+		DefaultTags:       pc.DefaultTags,
+		AssumeRoleARN:     pc.AssumeRoleARN,
+		PathStyleArgument: "s3_force_path_style",
+	}
+	if pc.UsePathStyle {
+		data.PathStyleArgument = "s3_use_path_style"
+	}
+	return data
+}
+
+// providerFuncs are available to the provider template
+var providerFuncs = template.FuncMap{
+	// quote returns s as a double quoted string literal
+	"quote": strconv.Quote,
+
+	// sortedKeys returns the keys of m in order
+	"sortedKeys": func(m map[string]string) []string {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	},
+}
+
+// providerTemplate is the default template of provider.tf
+const providerTemplate = `terraform {
+	backend "local" {
+		path = {{quote .State}}
+	}
+{{- if .Version}}
+
+	required_providers {
+		aws = {
+			source  = "hashicorp/aws"
+			version = {{quote .Version}}
+		}
+	}
+{{- end}}
+}
+{{range .Providers}}
+provider "aws" {
+{{- if .Alias}}
+	alias  = {{quote .Alias}}
+{{- end}}
+{{- if .Region}}
+	region = {{quote .Region}}
+{{- end}}
+	{{$.PathStyleArgument}} = true
+	skip_credentials_validation = true
+	skip_metadata_api_check     = true
+	skip_requesting_account_id  = true
+	endpoints {
+{{- range $.Services}}
+		{{.}} = {{quote $.Endpoint}}
+{{- end}}
+	}
+{{- if $.AssumeRoleARN}}
+	assume_role {
+		role_arn = {{quote $.AssumeRoleARN}}
+	}
+{{- end}}
+{{- if $.DefaultTags}}
+	default_tags {
+		tags = {
+{{- range $k := sortedKeys $.DefaultTags}}
+			{{quote $k}} = {{quote (index $.DefaultTags $k)}}
+{{- end}}
+		}
+	}
+{{- end}}
+}
+{{end -}}
+`
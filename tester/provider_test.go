@@ -0,0 +1,78 @@
+package tester
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetProvider(t *testing.T) {
+	tt := map[string]struct {
+		pc         *ProviderConfig
+		expected   []string
+		unexpected []string
+	}{
+		"default": {
+			expected: []string{
+				`path = "terraform.tfstate"`,
+				`s3_force_path_style = true`,
+				`kms = "http://localhost:5000"`,
+			},
+			unexpected: []string{"required_providers", "alias", "default_tags", "assume_role"},
+		},
+		"configured": {
+			pc: &ProviderConfig{
+				Version:       "~> 4.0",
+				Region:        "us-east-1",
+				Aliases:       []ProviderAlias{{Alias: "replica", Region: "us-west-2"}},
+				DefaultTags:   map[string]string{"b": "2", "a": "1"},
+				AssumeRoleARN: "arn:aws:iam::123456789012:role/test",
+				UsePathStyle:  true,
+			},
+			expected: []string{
+				`version = "~> 4.0"`,
+				`region = "us-east-1"`,
+				`alias  = "replica"`,
+				`region = "us-west-2"`,
+				`s3_use_path_style = true`,
+				`role_arn = "arn:aws:iam::123456789012:role/test"`,
+				"\"a\" = \"1\"\n\t\t\t\"b\" = \"2\"",
+			},
+			unexpected: []string{"s3_force_path_style"},
+		},
+		"template": {
+			pc:       &ProviderConfig{Template: `{{.Endpoint}} {{len .Providers}}`},
+			expected: []string{"http://localhost:5000 1"},
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			data, err := getProvider(tc.pc, []string{"kms", "s3"}, "http://localhost:5000", "terraform.tfstate")
+			if err != nil {
+				t.Fatalf("failed to get provider: %v", err)
+			}
+			for _, e := range tc.expected {
+				if !strings.Contains(string(data), e) {
+					t.Errorf("provider is missing: %s, got: %s", e, data)
+				}
+			}
+			for _, u := range tc.unexpected {
+				if strings.Contains(string(data), u) {
+					t.Errorf("provider must not contain: %s, got: %s", u, data)
+				}
+			}
+		})
+	}
+}
+
+func TestProviderCount(t *testing.T) {
+	pc := &ProviderConfig{Aliases: []ProviderAlias{{Alias: "replica"}, {Alias: "us-west-2"}}}
+	data, err := getProvider(pc, []string{"s3"}, "http://localhost:5000", "terraform.tfstate")
+	if err != nil {
+		t.Fatalf("failed to get provider: %v", err)
+	}
+	if c := strings.Count(string(data), `provider "aws"`); c != 3 {
+		t.Errorf("provider count invalid, expected: 3, got: %d", c)
+	}
+}
@@ -0,0 +1,74 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// report prints the final status of every job and the version of the
+// driver, failed jobs include the location of their logs and the last
+// lines of their output, failed and flaky jobs include the error of
+// every attempt, passed jobs include their resource coverage, it
+// returns true if any job failed
+func report(w io.Writer, version string, jobs []*job) bool {
+	var b strings.Builder
+	b.WriteString("\n\n\n\n===== Job Results =====\n")
+	fmt.Fprintf(&b, "%-20s%s\n", "DRIVER", version)
+
+	var failed bool
+	for _, j := range jobs {
+		if j.Skipped {
+			fmt.Fprintf(&b, "%-20s%-15s\n", j.Name, "SKIPPED")
+			continue
+		}
+		if j.Err != nil {
+			failed = true
+			fmt.Fprintf(&b, "%-20s%-15s%v\n", j.Name, "FAILED", j.Err)
+			reportAttempts(&b, j)
+			reportFailure(&b, j)
+			continue
+		}
+		if j.flaky() {
+			fmt.Fprintf(&b, "%-20s%-15spassed on attempt %d\n", j.Name, "FLAKY", len(j.Attempts)+1)
+			reportAttempts(&b, j)
+			reportResources(&b, j)
+			continue
+		}
+		fmt.Fprintf(&b, "%-20s%-15s\n", j.Name, "SUCCESS")
+		reportResources(&b, j)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		fmt.Printf("failed to write job results: %v\n", err)
+	}
+	return failed
+}
+
+// reportAttempts prints the error of each failed attempt of the job
+func reportAttempts(b *strings.Builder, j *job) {
+	for i, err := range j.Attempts {
+		fmt.Fprintf(b, "%-20s%-15s%v\n", "", fmt.Sprintf("ATTEMPT %d", i+1), err)
+	}
+}
+
+// reportFailure prints the location of the logs, state, and artifacts
+// of a failed job followed by the last lines of its output
+func reportFailure(b *strings.Builder, j *job) {
+	if len(j.LogDir) > 0 {
+		fmt.Fprintf(b, "%-20s%-15s%s\n", "", "LOGS", j.LogDir)
+	}
+	if len(j.StateDump) > 0 {
+		fmt.Fprintf(b, "%-20s%-15s%s\n", "", "STATE", j.StateDump)
+	}
+	if len(j.Artifacts) > 0 {
+		fmt.Fprintf(b, "%-20s%-15s%s\n", "", "ARTIFACTS", j.Artifacts)
+	}
+	if tail := j.tail.String(); len(tail) > 0 {
+		fmt.Fprintf(b, "%-20s%-15s\n", "", "OUTPUT")
+		for _, line := range strings.Split(tail, "\n") {
+			fmt.Fprintf(b, "%-20s%s\n", "", line)
+		}
+	}
+}
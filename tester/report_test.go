@@ -0,0 +1,87 @@
+package tester
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	failedJob := &job{Name: "failed", Err: errors.New("exit status 1"), LogDir: "/tmp/logs/failed", StateDump: "/tmp/logs/failed/emulator-state.json", tail: &tailBuffer{}}
+	failedJob.tail.add("--- FAIL: TestBucket")
+	failedJob.tail.add("FAIL")
+
+	tt := map[string]struct {
+		jobs     []*job
+		failed   bool
+		expected []string
+	}{
+		"success": {
+			jobs: []*job{
+				{Name: "passed"},
+				{Name: "skipped", Skipped: true},
+			},
+			expected: []string{
+				"DRIVER              terraform 1.5.7",
+				"passed              SUCCESS",
+				"skipped             SKIPPED",
+			},
+		},
+		"flaky": {
+			jobs: []*job{
+				{Name: "flaky", Attempts: []error{errors.New("status code: 500")}},
+			},
+			expected: []string{
+				"flaky               FLAKY          passed on attempt 2",
+				"                    ATTEMPT 1      status code: 500",
+			},
+		},
+		"resources": {
+			jobs: []*job{
+				{Name: "resources", Resources: &resourceCoverage{Total: 2, Untouched: []string{"aws_kms_key.this"}}},
+			},
+			expected: []string{
+				"resources           SUCCESS",
+				"                    RESOURCES      50.0% of 2 resources asserted",
+				"                    UNTOUCHED      aws_kms_key.this",
+			},
+		},
+		"retried": {
+			jobs: []*job{
+				{Name: "retried", Err: errors.New("exit status 1"), Attempts: []error{errors.New("status code: 500")}},
+			},
+			failed: true,
+			expected: []string{
+				"retried             FAILED         exit status 1",
+				"                    ATTEMPT 1      status code: 500",
+			},
+		},
+		"failed": {
+			jobs:   []*job{failedJob},
+			failed: true,
+			expected: []string{
+				"failed              FAILED         exit status 1",
+				"                    LOGS           /tmp/logs/failed",
+				"                    STATE          /tmp/logs/failed/emulator-state.json",
+				"                    --- FAIL: TestBucket",
+				"                    FAIL",
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			out := &strings.Builder{}
+			failed := report(out, "terraform 1.5.7", tc.jobs)
+			if failed != tc.failed {
+				t.Errorf("failed invalid, expected: %t, got: %t", tc.failed, failed)
+			}
+			for _, line := range tc.expected {
+				if !strings.Contains(out.String(), line) {
+					t.Errorf("report missing line: %q, got: %s", line, out.String())
+				}
+			}
+		})
+	}
+}
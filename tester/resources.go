@@ -0,0 +1,189 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GSA/grace-tftest/aws/shared"
+	"github.com/GSA/grace-tftest/tfplan"
+)
+
+// ledgerFile is the name of the ledger of each job that the aws
+// packages record the resources selected by assertions into
+const ledgerFile = "ledger.txt"
+
+// setLedger directs the aws packages used by go test to record the
+// resources they select into the artifacts directory of the job
+func (j *job) setLedger(cfg *Config) {
+	if !cfg.ResourceCoverage || len(j.LogDir) == 0 {
+		return
+	}
+	j.Ledger = filepath.Join(j.LogDir, ledgerFile)
+	j.Env = append(j.Env, shared.LedgerEnv+"="+j.Ledger)
+}
+
+// resourceCoverage is the number of managed resources in the state of
+// a job and the addresses of those that no assertion selected
+type resourceCoverage struct {
+	Total     int
+	Untouched []string
+}
+
+func (c *resourceCoverage) percent() float64 {
+	if c.Total == 0 {
+		return 100
+	}
+	return 100 * float64(c.Total-len(c.Untouched)) / float64(c.Total)
+}
+
+func (c *resourceCoverage) String() string {
+	return fmt.Sprintf("%.1f%% of %d resources asserted", c.percent(), c.Total)
+}
+
+// shownState is the output of `terraform show -json` for the state
+type shownState struct {
+	Values *tfplan.Values `json:"values"`
+}
+
+// managedResources returns the managed resources of m and its child
+// modules, data sources are not deployed so they are excluded
+func managedResources(m tfplan.Module) []tfplan.Planned {
+	var resources []tfplan.Planned
+	for _, r := range m.Resources {
+		if r.Mode == "managed" {
+			resources = append(resources, r)
+		}
+	}
+	for _, child := range m.ChildModules {
+		resources = append(resources, managedResources(child)...)
+	}
+	return resources
+}
+
+// touched returns true if an entry of the ledger has the type of the
+// resource and every one of its IDs is the value of a top level
+// attribute of the resource
+func touched(r tfplan.Planned, entries []shared.LedgerEntry) bool {
+	values := make(map[string]bool)
+	for _, v := range r.Values {
+		if s, ok := v.(string); ok {
+			values[s] = true
+		}
+	}
+
+outer:
+	for _, e := range entries {
+		if e.Kind != r.Type {
+			continue
+		}
+		for _, id := range e.IDs {
+			if !values[id] {
+				continue outer
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compareLedger returns the coverage of the managed resources in
+// the state by the entries of the ledger
+func compareLedger(state *shownState, entries []shared.LedgerEntry) *resourceCoverage {
+	c := &resourceCoverage{}
+	if state.Values == nil {
+		return c
+	}
+	for _, r := range managedResources(state.Values.RootModule) {
+		c.Total++
+		if !touched(r, entries) {
+			c.Untouched = append(c.Untouched, r.Address)
+		}
+	}
+	sort.Strings(c.Untouched)
+	return c
+}
+
+// readLedger reads the entries recorded by go test, a test that
+// selected nothing never creates the ledger
+func readLedger(path string) ([]shared.LedgerEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if ignoreNotExistsErr(err) == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open ledger: %q -> %v", path, err)
+	}
+	defer f.Close()
+
+	entries, err := shared.ParseLedger(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ledger: %q -> %v", path, err)
+	}
+	return entries, nil
+}
+
+// checkResources compares the ledger of the job against its state and
+// fails the job when its coverage is below Config.ResourceThreshold
+func (j *job) checkResources(ctx context.Context, cfg *Config) error {
+	data, err := j.captureStep(ctx, j.dir(), StepShow)
+	if err != nil {
+		return err
+	}
+	state := &shownState{}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return fmt.Errorf("failed to parse state: %v", err)
+	}
+
+	entries, err := readLedger(j.Ledger)
+	if err != nil {
+		return err
+	}
+
+	c := compareLedger(state, entries)
+	j.Resources = c
+	j.printf("%s\n", c)
+	if cfg.ResourceThreshold > 0 && c.percent() < cfg.ResourceThreshold {
+		return fmt.Errorf("resource coverage %.1f%% is below the threshold of %.1f%%, not asserted: %s",
+			c.percent(), cfg.ResourceThreshold, strings.Join(c.Untouched, ", "))
+	}
+	return nil
+}
+
+// withResourceCoverage empties the ledger before test runs and checks
+// the resources that it selected once it passes
+func (j *job) withResourceCoverage(cfg *Config, test func(context.Context) error) func(context.Context) error {
+	if len(j.Ledger) == 0 {
+		return test
+	}
+	return func(ctx context.Context) error {
+		j.Resources = nil
+		err := ignoreNotExistsErr(os.Remove(j.Ledger))
+		if err != nil {
+			return fmt.Errorf("failed to remove ledger: %q -> %v", j.Ledger, err)
+		}
+
+		err = test(ctx)
+		if err != nil {
+			return err
+		}
+		return j.checkResources(ctx, cfg)
+	}
+}
+
+// reportResources prints the resource coverage of the job followed
+// by the address of every resource that no assertion selected
+func reportResources(b *strings.Builder, j *job) {
+	if j.Resources == nil {
+		return
+	}
+	fmt.Fprintf(b, "%-20s%-15s%s\n", "", "RESOURCES", j.Resources)
+	for _, address := range j.Resources.Untouched {
+		fmt.Fprintf(b, "%-20s%-15s%s\n", "", "UNTOUCHED", address)
+	}
+}
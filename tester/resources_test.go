@@ -0,0 +1,173 @@
+package tester
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/GSA/grace-tftest/aws/shared"
+)
+
+const shownStateJSON = `{
+  "format_version": "1.0",
+  "values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_kms_key.this",
+          "mode": "managed",
+          "type": "aws_kms_key",
+          "values": {"id": "1234", "arn": "arn:aws:kms:us-east-1:123456789012:key/1234"}
+        },
+        {
+          "address": "aws_s3_bucket_policy.logs",
+          "mode": "managed",
+          "type": "aws_s3_bucket_policy",
+          "values": {"id": "logs", "bucket": "logs"}
+        },
+        {
+          "address": "data.aws_caller_identity.current",
+          "mode": "data",
+          "type": "aws_caller_identity",
+          "values": {"id": "123456789012"}
+        }
+      ],
+      "child_modules": [
+        {
+          "address": "module.role",
+          "resources": [
+            {
+              "address": "module.role.aws_iam_role_policy_attachment.this",
+              "mode": "managed",
+              "type": "aws_iam_role_policy_attachment",
+              "values": {"id": "role-1", "role": "role", "policy_arn": "arn:aws:iam::aws:policy/a"}
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func TestCompareLedger(t *testing.T) {
+	state := &shownState{}
+	err := json.Unmarshal([]byte(shownStateJSON), state)
+	if err != nil {
+		t.Fatalf("failed to parse state: %v", err)
+	}
+
+	tt := map[string]struct {
+		entries   []shared.LedgerEntry
+		untouched []string
+		percent   float64
+	}{
+		"none": {
+			untouched: []string{
+				"aws_kms_key.this",
+				"aws_s3_bucket_policy.logs",
+				"module.role.aws_iam_role_policy_attachment.this",
+			},
+			percent: 0,
+		},
+		"all": {
+			entries: []shared.LedgerEntry{
+				{Kind: "aws_kms_key", IDs: []string{"1234"}},
+				{Kind: "aws_s3_bucket_policy", IDs: []string{"logs"}},
+				{Kind: "aws_iam_role_policy_attachment", IDs: []string{"role", "arn:aws:iam::aws:policy/a"}},
+			},
+			percent: 100,
+		},
+		"type mismatch": {
+			entries: []shared.LedgerEntry{
+				{Kind: "aws_s3_bucket", IDs: []string{"logs"}},
+				{Kind: "aws_kms_key", IDs: []string{"1234"}},
+			},
+			untouched: []string{
+				"aws_s3_bucket_policy.logs",
+				"module.role.aws_iam_role_policy_attachment.this",
+			},
+			percent: 100.0 / 3,
+		},
+		"partial ids": {
+			entries: []shared.LedgerEntry{
+				{Kind: "aws_iam_role_policy_attachment", IDs: []string{"other", "arn:aws:iam::aws:policy/a"}},
+			},
+			untouched: []string{
+				"aws_kms_key.this",
+				"aws_s3_bucket_policy.logs",
+				"module.role.aws_iam_role_policy_attachment.this",
+			},
+			percent: 0,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			c := compareLedger(state, tc.entries)
+			if c.Total != 3 {
+				t.Errorf("total invalid, expected: 3, got: %d", c.Total)
+			}
+			if !reflect.DeepEqual(c.Untouched, tc.untouched) {
+				t.Errorf("untouched invalid, expected: %v, got: %v", tc.untouched, c.Untouched)
+			}
+			if c.percent() != tc.percent {
+				t.Errorf("percent invalid, expected: %.1f, got: %.1f", tc.percent, c.percent())
+			}
+		})
+	}
+}
+
+func TestCompareLedgerEmptyState(t *testing.T) {
+	c := compareLedger(&shownState{}, nil)
+	if c.Total != 0 || c.percent() != 100 {
+		t.Errorf("coverage invalid, expected: 100.0%% of 0, got: %s", c)
+	}
+}
+
+func TestReadLedger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledger")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ledgerFile)
+	entries, err := readLedger(path)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("missing ledger invalid, expected no entries, got: %v, %v", entries, err)
+	}
+
+	err = ioutil.WriteFile(path, []byte("aws_kms_key\t1234\n"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write ledger: %v", err)
+	}
+	entries, err = readLedger(path)
+	if err != nil {
+		t.Fatalf("failed to read ledger: %v", err)
+	}
+	expected := []shared.LedgerEntry{{Kind: "aws_kms_key", IDs: []string{"1234"}}}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("entries invalid, expected: %v, got: %v", expected, entries)
+	}
+}
+
+func TestSetLedger(t *testing.T) {
+	j := &job{LogDir: "/tmp/logs/ledger"}
+	j.setLedger(&Config{})
+	if len(j.Ledger) != 0 || len(j.Env) != 0 {
+		t.Fatalf("ledger set without ResourceCoverage: %s %v", j.Ledger, j.Env)
+	}
+
+	j.setLedger(&Config{ResourceCoverage: true})
+	expected := filepath.Join(j.LogDir, ledgerFile)
+	if j.Ledger != expected {
+		t.Errorf("ledger invalid, expected: %s, got: %s", expected, j.Ledger)
+	}
+	if len(j.Env) != 1 || j.Env[0] != shared.LedgerEnv+"="+expected {
+		t.Errorf("env invalid, expected: %s=%s, got: %v", shared.LedgerEnv, expected, j.Env)
+	}
+}
@@ -0,0 +1,77 @@
+package tester
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// retries returns the number of times the job is run again after
+// failing, the job config takes precedence over Tester
+func (j *job) retries(cfg *Config) int {
+	if j.Config != nil && j.Config.Retries != nil {
+		return *j.Config.Retries
+	}
+	return cfg.Retries
+}
+
+// runAttempts runs the job until it succeeds or runs out of retries,
+// the errors of the failed attempts before the last are kept in Attempts
+func (j *job) runAttempts(cfg *Config) error {
+	// the environment and variables are extended
+	// by each attempt so they are restored before
+	// the next one
+	env := append([]string{}, j.Env...)
+	args := append([]string{}, j.VarArgs...)
+
+	retries := j.retries(cfg)
+	for attempt := 1; ; attempt++ {
+		err := j.run(cfg)
+		if err == nil || attempt > retries || atomic.LoadInt32(&cfg.interrupted) == 1 {
+			return err
+		}
+
+		j.printf("attempt %d of %d failed, retrying: %v\n", attempt, retries+1, err)
+		j.mu.Lock()
+		j.Attempts = append(j.Attempts, err)
+		j.mu.Unlock()
+		j.reset(env, args)
+	}
+}
+
+// reset removes everything left behind by a failed attempt so the
+// next attempt starts from a fresh workspace and emulator
+func (j *job) reset(env, args []string) {
+	// wait for the output of every process to be
+	// read before the log files are replaced
+	j.cleanupProcesses()
+	j.mu.Lock()
+	processes := j.Processes
+	j.mu.Unlock()
+	for _, p := range processes {
+		_ = p.Wait()
+	}
+	j.removeWorkspace(false)
+
+	// a new log file is opened by the next attempt,
+	// which appends to the output of this attempt
+	err := j.logs.close()
+	if err != nil {
+		fmt.Printf("[%s]: %v\n", j.Name, err)
+	}
+	j.tail.reset()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Env = append([]string{}, env...)
+	j.VarArgs = append([]string{}, args...)
+	j.Processes = nil
+	j.Services = nil
+	j.WorkDir = ""
+	j.emulator = nil
+	j.logs = nil
+}
+
+// flaky returns true if the job passed after a failed attempt
+func (j *job) flaky() bool {
+	return j.Err == nil && len(j.Attempts) > 0
+}
@@ -0,0 +1,82 @@
+package tester
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJobRetries(t *testing.T) {
+	zero, two := 0, 2
+
+	tt := map[string]struct {
+		cfg      *Config
+		jc       *JobConfig
+		expected int
+	}{
+		"none": {
+			cfg:      &Config{},
+			jc:       &JobConfig{},
+			expected: 0,
+		},
+		"config": {
+			cfg:      &Config{Retries: 3},
+			jc:       &JobConfig{},
+			expected: 3,
+		},
+		"job": {
+			cfg:      &Config{Retries: 3},
+			jc:       &JobConfig{Retries: &two},
+			expected: 2,
+		},
+		"disabled": {
+			cfg:      &Config{Retries: 3},
+			jc:       &JobConfig{Retries: &zero},
+			expected: 0,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			j := &job{Config: tc.jc}
+			actual := j.retries(tc.cfg)
+			if actual != tc.expected {
+				t.Errorf("retries invalid, expected: %d, got: %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJobReset(t *testing.T) {
+	j := newJob("reset", "", "", "", []string{"A=1"}, &JobConfig{})
+	j.VarArgs = []string{"-var-file=a.tfvars"}
+	env := append([]string{}, j.Env...)
+	args := append([]string{}, j.VarArgs...)
+
+	j.Env = append(j.Env, "MOTO_PORT=5000")
+	j.VarArgs = append([]string{"-var=id=1"}, j.VarArgs...)
+	j.Services = []string{"s3"}
+	j.tail.add("failed")
+	j.reset(env, args)
+
+	if len(j.Env) != 1 || j.Env[0] != "A=1" {
+		t.Errorf("env invalid, expected: [A=1], got: %v", j.Env)
+	}
+	if len(j.VarArgs) != 1 || j.VarArgs[0] != "-var-file=a.tfvars" {
+		t.Errorf("var args invalid, expected: [-var-file=a.tfvars], got: %v", j.VarArgs)
+	}
+	if j.Services != nil {
+		t.Errorf("services invalid, expected: nil, got: %v", j.Services)
+	}
+	if tail := j.tail.String(); len(tail) > 0 {
+		t.Errorf("tail invalid, expected empty, got: %q", tail)
+	}
+	if j.flaky() {
+		t.Errorf("flaky invalid, expected: false, got: true")
+	}
+	j.Err = nil
+	j.Attempts = []error{errors.New("failed")}
+	if !j.flaky() {
+		t.Errorf("flaky invalid, expected: true, got: false")
+	}
+}
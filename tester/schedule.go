@@ -0,0 +1,149 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// durations are the recorded run times of jobs in seconds by job name
+type durations map[string]float64
+
+// defaultDurationsFile returns a file inside of tftest in the user
+// cache directory that is unique to the absolute path of dir
+func defaultDurationsFile(dir string) string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(abs))
+	return filepath.Join(cache, "tftest", fmt.Sprintf("durations-%08x.json", h.Sum32()))
+}
+
+// readDurations reads the durations recorded in path,
+// a missing file results in no recorded durations
+func readDurations(path string) (durations, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if ignoreNotExistsErr(err) == nil {
+			return durations{}, nil
+		}
+		return nil, fmt.Errorf("failed to read durations: %q -> %v", path, err)
+	}
+
+	d := durations{}
+	err = json.Unmarshal(data, &d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse durations: %q -> %v", path, err)
+	}
+	return d, nil
+}
+
+// record adds the run time of every job that finished
+func (d durations) record(jobs []*job) {
+	for _, j := range jobs {
+		j.mu.Lock()
+		if !j.Started.IsZero() && !j.Finished.IsZero() {
+			d[j.Name] = j.Finished.Sub(j.Started).Seconds()
+		}
+		j.mu.Unlock()
+	}
+}
+
+// write stores the durations in path
+func (d durations) write(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %q -> %v", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode durations: %v", err)
+	}
+	err = ioutil.WriteFile(path, data, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write durations: %q -> %v", path, err)
+	}
+	return nil
+}
+
+// scheduleJobs returns the jobs in the order they are started, the
+// longest recorded jobs run first so they are not left to run alone
+// at the end, jobs without a recorded duration are started before
+// all others since their duration is unknown
+func scheduleJobs(jobs []*job, d durations) []*job {
+	ordered := append([]*job{}, jobs...)
+	sort.SliceStable(ordered, func(a, b int) bool {
+		da, oka := d[ordered[a].Name]
+		db, okb := d[ordered[b].Name]
+		if oka != okb {
+			return !oka
+		}
+		return da > db
+	})
+	return ordered
+}
+
+// capacity returns the number of slots shared by the running jobs
+func capacity(cfg *Config) int {
+	if cfg.MaxJobs > 0 {
+		return cfg.MaxJobs
+	}
+	return runtime.NumCPU() * cfg.JobsPerCPU
+}
+
+// weight returns the number of slots used by the job, which
+// is limited to the capacity so every job is able to run
+func (j *job) weight(capacity int) int {
+	w := 1
+	if j.Config != nil && j.Config.Weight > 1 {
+		w = j.Config.Weight
+	}
+	if w > capacity {
+		w = capacity
+	}
+	return w
+}
+
+// slots is a counting semaphore that is acquired by jobs
+// using the number of slots of their weight
+type slots struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	free int
+}
+
+func newSlots(n int) *slots {
+	s := &slots{free: n}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// acquire blocks until n slots are free
+func (s *slots) acquire(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.free < n {
+		s.cond.Wait()
+	}
+	s.free -= n
+}
+
+// release frees n slots
+func (s *slots) release(n int) {
+	s.mu.Lock()
+	s.free += n
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
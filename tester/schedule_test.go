@@ -0,0 +1,113 @@
+package tester
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduleJobs(t *testing.T) {
+	jobs := []*job{{Name: "short"}, {Name: "new"}, {Name: "long"}, {Name: "medium"}, {Name: "other"}}
+	recorded := durations{"short": 10, "long": 300, "medium": 60}
+
+	var actual []string
+	for _, j := range scheduleJobs(jobs, recorded) {
+		actual = append(actual, j.Name)
+	}
+	expected := []string{"new", "other", "long", "medium", "short"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("order invalid, expected: %v, got: %v", expected, actual)
+	}
+	if jobs[0].Name != "short" {
+		t.Errorf("jobs were reordered in place")
+	}
+}
+
+func TestJobWeight(t *testing.T) {
+	tt := map[string]struct {
+		jc       *JobConfig
+		expected int
+	}{
+		"default": {jc: &JobConfig{}, expected: 1},
+		"nil":     {expected: 1},
+		"heavy":   {jc: &JobConfig{Weight: 3}, expected: 3},
+		"capped":  {jc: &JobConfig{Weight: 10}, expected: 4},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual := (&job{Config: tc.jc}).weight(4)
+			if actual != tc.expected {
+				t.Errorf("weight invalid, expected: %d, got: %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	if actual := capacity(&Config{MaxJobs: 3, JobsPerCPU: 100}); actual != 3 {
+		t.Errorf("capacity invalid, expected: 3, got: %d", actual)
+	}
+}
+
+func TestSlots(t *testing.T) {
+	s := newSlots(3)
+	s.acquire(2)
+
+	acquired := make(chan struct{})
+	go func() {
+		s.acquire(2)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("acquired more slots than are free")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.release(2)
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("failed to acquire released slots")
+	}
+}
+
+func TestDurations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "durations")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache", "durations.json")
+
+	d, err := readDurations(path)
+	if err != nil {
+		t.Fatalf("failed to read missing durations: %v", err)
+	}
+
+	now := time.Now()
+	d["previous"] = 5
+	d.record([]*job{
+		{Name: "finished", Started: now.Add(-90 * time.Second), Finished: now},
+		{Name: "pending"},
+	})
+	err = d.write(path)
+	if err != nil {
+		t.Fatalf("failed to write durations: %v", err)
+	}
+
+	actual, err := readDurations(path)
+	if err != nil {
+		t.Fatalf("failed to read durations: %v", err)
+	}
+	expected := durations{"finished": 90, "previous": 5}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("durations invalid, expected: %v, got: %v", expected, actual)
+	}
+}
@@ -0,0 +1,119 @@
+package tester
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ShardEnv is the ENV variable used for Config.Shard when it is not set
+const ShardEnv = "TFTEST_SHARD"
+
+// shard is a 1-based index into count equal parts of the jobs
+type shard struct {
+	index int
+	count int
+}
+
+// parseShard parses a shard in the form index/count, for example: 2/5
+func parseShard(s string) (*shard, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid shard: %q, expected index/count", s)
+	}
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard index: %q -> %v", s, err)
+	}
+	count, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard count: %q -> %v", s, err)
+	}
+	if count < 1 || index < 1 || index > count {
+		return nil, fmt.Errorf("invalid shard: %q, index must be between 1 and count", s)
+	}
+	return &shard{index: index, count: count}, nil
+}
+
+// contains returns true if name hashes into the shard, the hash
+// is stable so a job always belongs to the same shard
+func (s *shard) contains(name string) bool {
+	if s == nil {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return int(h.Sum32()%uint32(s.count)) == s.index-1
+}
+
+// selectJobs marks every job that was not selected by the
+// Include, Exclude, Tags, ExcludeTags, and Shard settings as skipped
+func selectJobs(cfg *Config, jobs []*job) error {
+	sh, err := parseShard(cfg.Shard)
+	if err != nil {
+		return err
+	}
+
+	for _, j := range jobs {
+		selected, err := isSelected(cfg, j)
+		if err != nil {
+			return err
+		}
+		if !selected || !sh.contains(j.Name) {
+			j.Skipped = true
+			j.Err = nil
+		}
+	}
+	return nil
+}
+
+func isSelected(cfg *Config, j *job) (bool, error) {
+	if len(cfg.Include) > 0 {
+		matched, err := matchAny(cfg.Include, j.Name)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	matched, err := matchAny(cfg.Exclude, j.Name)
+	if err != nil || matched {
+		return false, err
+	}
+
+	if len(cfg.Tags) > 0 && !hasAny(j.Config.Tags, cfg.Tags) {
+		return false, nil
+	}
+
+	return !hasAny(j.Config.Tags, cfg.ExcludeTags), nil
+}
+
+// matchAny returns true if name matches any of the glob patterns
+func matchAny(patterns []string, name string) (bool, error) {
+	for _, p := range patterns {
+		matched, err := filepath.Match(p, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid job pattern: %q -> %v", p, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hasAny returns true if any value in a is also in b
+func hasAny(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package tester
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseShard(t *testing.T) {
+	tt := map[string]struct {
+		in          string
+		expected    *shard
+		expectedErr bool
+	}{
+		"empty":       {in: "", expected: nil},
+		"valid":       {in: "2/5", expected: &shard{index: 2, count: 5}},
+		"zero_index":  {in: "0/5", expectedErr: true},
+		"large_index": {in: "6/5", expectedErr: true},
+		"malformed":   {in: "2", expectedErr: true},
+		"not_number":  {in: "a/b", expectedErr: true},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual, err := parseShard(tc.in)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("error invalid, expected error: %t, got: %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("shard invalid, expected: %v, got: %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestShardContains(t *testing.T) {
+	// every job must belong to exactly one shard
+	count := 5
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("job%d", i)
+		var found int
+		for index := 1; index <= count; index++ {
+			if (&shard{index: index, count: count}).contains(name) {
+				found++
+			}
+		}
+		if found != 1 {
+			t.Errorf("job %s belongs to %d shards", name, found)
+		}
+	}
+}
+
+func TestSelectJobs(t *testing.T) {
+	newJobs := func() []*job {
+		return []*job{
+			{Name: "bucket", Config: &JobConfig{}},
+			{Name: "kms", Config: &JobConfig{Tags: []string{"kms"}}},
+			{Name: "cloudtrail", Config: &JobConfig{Tags: []string{"slow", "kms"}}},
+		}
+	}
+
+	tt := map[string]struct {
+		cfg      *Config
+		expected []string
+	}{
+		"all":          {cfg: &Config{}, expected: []string{"bucket", "kms", "cloudtrail"}},
+		"include":      {cfg: &Config{Include: []string{"k*"}}, expected: []string{"kms"}},
+		"exclude":      {cfg: &Config{Exclude: []string{"k*", "bucket"}}, expected: []string{"cloudtrail"}},
+		"tags":         {cfg: &Config{Tags: []string{"kms"}}, expected: []string{"kms", "cloudtrail"}},
+		"exclude_tags": {cfg: &Config{ExcludeTags: []string{"slow"}}, expected: []string{"bucket", "kms"}},
+		"one_shard":    {cfg: &Config{Shard: "1/1"}, expected: []string{"bucket", "kms", "cloudtrail"}},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			jobs := newJobs()
+			err := selectJobs(tc.cfg, jobs)
+			if err != nil {
+				t.Fatalf("failed to select jobs: %v", err)
+			}
+			var actual []string
+			for _, j := range jobs {
+				if !j.Skipped {
+					actual = append(actual, j.Name)
+				}
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("selected jobs invalid, expected: %v, got: %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReadJobConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jobconfig")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	jc, err := readJobConfig(dir)
+	if err != nil {
+		t.Fatalf("failed to read missing job config: %v", err)
+	}
+	if len(jc.Tags) != 0 {
+		t.Errorf("tags invalid, expected none, got: %v", jc.Tags)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, JobConfigFile), []byte(`{"tags": ["slow"]}`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write job config: %v", err)
+	}
+	jc, err = readJobConfig(dir)
+	if err != nil {
+		t.Fatalf("failed to read job config: %v", err)
+	}
+	if !reflect.DeepEqual(jc.Tags, []string{"slow"}) {
+		t.Errorf("tags invalid, expected: [slow], got: %v", jc.Tags)
+	}
+}
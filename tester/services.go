@@ -0,0 +1,369 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ServiceSupporter is implemented by emulators that know which
+// Terraform AWS Provider custom endpoints they implement
+type ServiceSupporter interface {
+	Supports(service string) bool
+}
+
+// resourceType matches the aws resource and data source types in .tf files
+var resourceType = regexp.MustCompile(`(?m)^\s*(?:resource|data)\s+"(aws_[a-z0-9_]+)"`)
+
+// noService contains the data sources that never call an AWS API
+var noService = map[string]bool{
+	"aws_arn":                     true,
+	"aws_iam_policy_document":     true,
+	"aws_partition":               true,
+	"aws_region":                  true,
+	"aws_regions":                 true,
+	"aws_default_tags":            true,
+	"aws_ip_ranges":               true,
+	"aws_billing_service_account": true,
+}
+
+// resourceServices maps resource types whose service differs from
+// the service of their prefix in resourcePrefixes
+var resourceServices = map[string]string{
+	"aws_cognito_identity_provider":            "cognitoidp",
+	"aws_launch_configuration":                 "autoscaling",
+	"aws_s3_access_point":                      "s3control",
+	"aws_s3_access_point_policy":               "s3control",
+	"aws_s3_multi_region_access_point":         "s3control",
+	"aws_s3_multi_region_access_point_policy":  "s3control",
+	"aws_s3_object_lambda_access_point":        "s3control",
+	"aws_s3_object_lambda_access_point_policy": "s3control",
+}
+
+// resourcePrefixes maps resource type prefixes to the custom endpoint
+// of their service, the longest matching prefix is used
+var resourcePrefixes = map[string]string{
+	"aws_acm_":                    "acm",
+	"aws_acmpca_":                 "acmpca",
+	"aws_ami":                     "ec2",
+	"aws_api_gateway_":            "apigateway",
+	"aws_appautoscaling_":         "applicationautoscaling",
+	"aws_athena_":                 "athena",
+	"aws_autoscaling_":            "autoscaling",
+	"aws_availability_zone":       "ec2",
+	"aws_backup_":                 "backup",
+	"aws_batch_":                  "batch",
+	"aws_budgets_":                "budgets",
+	"aws_caller_identity":         "sts",
+	"aws_cloudformation_":         "cloudformation",
+	"aws_cloudfront_":             "cloudfront",
+	"aws_cloudtrail":              "cloudtrail",
+	"aws_cloudwatch_dashboard":    "cloudwatch",
+	"aws_cloudwatch_event_":       "cloudwatchevents",
+	"aws_cloudwatch_log_":         "cloudwatchlogs",
+	"aws_cloudwatch_metric_alarm": "cloudwatch",
+	"aws_codebuild_":              "codebuild",
+	"aws_codecommit_":             "codecommit",
+	"aws_codedeploy_":             "codedeploy",
+	"aws_codepipeline":            "codepipeline",
+	"aws_cognito_identity_":       "cognitoidentity",
+	"aws_cognito_user_":           "cognitoidp",
+	"aws_config_":                 "configservice",
+	"aws_customer_gateway":        "ec2",
+	"aws_datapipeline_":           "datapipeline",
+	"aws_datasync_":               "datasync",
+	"aws_db_":                     "rds",
+	"aws_default_":                "ec2",
+	"aws_dynamodb_":               "dynamodb",
+	"aws_ebs_":                    "ec2",
+	"aws_ec2_":                    "ec2",
+	"aws_ecr_":                    "ecr",
+	"aws_ecs_":                    "ecs",
+	"aws_efs_":                    "efs",
+	"aws_egress_only_internet_":   "ec2",
+	"aws_eip":                     "ec2",
+	"aws_eks_":                    "eks",
+	"aws_elasticache_":            "elasticache",
+	"aws_elastic_beanstalk_":      "elasticbeanstalk",
+	"aws_elasticsearch_":          "es",
+	"aws_elb":                     "elb",
+	"aws_emr_":                    "emr",
+	"aws_flow_log":                "ec2",
+	"aws_glacier_":                "glacier",
+	"aws_glue_":                   "glue",
+	"aws_guardduty_":              "guardduty",
+	"aws_iam_":                    "iam",
+	"aws_instance":                "ec2",
+	"aws_internet_gateway":        "ec2",
+	"aws_iot_":                    "iot",
+	"aws_key_pair":                "ec2",
+	"aws_kinesis_firehose_":       "firehose",
+	"aws_kinesis_stream":          "kinesis",
+	"aws_kinesis_video_":          "kinesisvideo",
+	"aws_kms_":                    "kms",
+	"aws_lambda_":                 "lambda",
+	"aws_launch_":                 "ec2",
+	"aws_main_route_table_":       "ec2",
+	"aws_nat_gateway":             "ec2",
+	"aws_network_":                "ec2",
+	"aws_organizations_":          "organizations",
+	"aws_placement_group":         "ec2",
+	"aws_ram_":                    "ram",
+	"aws_rds_":                    "rds",
+	"aws_redshift_":               "redshift",
+	"aws_resourcegroups_":         "resourcegroups",
+	"aws_route":                   "ec2",
+	"aws_route53_":                "route53",
+	"aws_route53_resolver_":       "route53resolver",
+	"aws_s3_":                     "s3",
+	"aws_s3_account_":             "s3control",
+	"aws_s3control_":              "s3control",
+	"aws_sagemaker_":              "sagemaker",
+	"aws_secretsmanager_":         "secretsmanager",
+	"aws_security_group":          "ec2",
+	"aws_securityhub_":            "securityhub",
+	"aws_ses_":                    "ses",
+	"aws_sfn_":                    "stepfunctions",
+	"aws_sns_":                    "sns",
+	"aws_sqs_":                    "sqs",
+	"aws_ssm_":                    "ssm",
+	"aws_subnet":                  "ec2",
+	"aws_volume_attachment":       "ec2",
+	"aws_vpc":                     "ec2",
+	"aws_vpn_":                    "ec2",
+}
+
+// serviceFor returns the custom endpoint used by the resource type
+// or false if the service of the resource type is not known
+func serviceFor(resource string) (string, bool) {
+	if s, ok := resourceServices[resource]; ok {
+		return s, true
+	}
+
+	var prefix string
+	for p := range resourcePrefixes {
+		if strings.HasPrefix(resource, p) && len(p) > len(prefix) {
+			prefix = p
+		}
+	}
+	if len(prefix) == 0 {
+		return "", false
+	}
+	return resourcePrefixes[prefix], true
+}
+
+// detectedServices contains the results of detectServices
+type detectedServices struct {
+	// Services are the custom endpoints required by the resources
+	Services []string
+
+	// Unknown are resource types without a known service
+	Unknown []string
+
+	// Types is the number of aws resource types found
+	Types int
+}
+
+// detectServices parses the .tf files inside of dir and inside of every
+// module installed by terraform init for the aws resource types in use
+func detectServices(dir string) (*detectedServices, error) {
+	dirs, err := moduleDirs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]bool)
+	for _, d := range append([]string{dir}, dirs...) {
+		err = parseResourceTypes(d, types)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the provider always calls sts unless requesting
+	// the account id is skipped by the provider config
+	services := map[string]bool{"sts": true}
+	result := &detectedServices{Types: len(types)}
+	for t := range types {
+		if noService[t] {
+			continue
+		}
+		s, ok := serviceFor(t)
+		if !ok {
+			result.Unknown = append(result.Unknown, t)
+			continue
+		}
+		services[s] = true
+	}
+	for s := range services {
+		result.Services = append(result.Services, s)
+	}
+	sort.Strings(result.Services)
+	sort.Strings(result.Unknown)
+	return result, nil
+}
+
+// parseResourceTypes adds the aws resource types used by the .tf files in dir
+func parseResourceTypes(dir string, types map[string]bool) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return fmt.Errorf("failed to list files in: %q -> %v", dir, err)
+	}
+	for _, m := range matches {
+		data, err := ioutil.ReadFile(m)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %q -> %v", m, err)
+		}
+		for _, match := range resourceType.FindAllSubmatch(data, -1) {
+			types[string(match[1])] = true
+		}
+	}
+	return nil
+}
+
+// moduleDirs returns the directories of the modules installed by
+// terraform init, read from the modules.json manifest
+func moduleDirs(dir string) ([]string, error) {
+	path := filepath.Join(dir, ".terraform", "modules", "modules.json")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if ignoreNotExistsErr(err) == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read module manifest: %q -> %v", path, err)
+	}
+
+	var manifest struct {
+		Modules []struct {
+			Key string `json:"Key"`
+			Dir string `json:"Dir"`
+		} `json:"Modules"`
+	}
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse module manifest: %q -> %v", path, err)
+	}
+
+	var dirs []string
+	for _, m := range manifest.Modules {
+		// the root module has an empty key
+		if len(m.Key) == 0 {
+			continue
+		}
+		d := m.Dir
+		if !filepath.IsAbs(d) {
+			d = filepath.Join(dir, d)
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+// motoServices are the custom endpoints implemented by moto_server
+var motoServices = map[string]bool{
+	"acm":                    true,
+	"apigateway":             true,
+	"applicationautoscaling": true,
+	"athena":                 true,
+	"autoscaling":            true,
+	"batch":                  true,
+	"budgets":                true,
+	"cloudformation":         true,
+	"cloudtrail":             true,
+	"cloudwatch":             true,
+	"cloudwatchevents":       true,
+	"cloudwatchlogs":         true,
+	"codecommit":             true,
+	"codepipeline":           true,
+	"cognitoidentity":        true,
+	"cognitoidp":             true,
+	"configservice":          true,
+	"datapipeline":           true,
+	"datasync":               true,
+	"dynamodb":               true,
+	"ec2":                    true,
+	"ecr":                    true,
+	"ecs":                    true,
+	"efs":                    true,
+	"eks":                    true,
+	"elasticbeanstalk":       true,
+	"elb":                    true,
+	"emr":                    true,
+	"firehose":               true,
+	"glacier":                true,
+	"glue":                   true,
+	"guardduty":              true,
+	"iam":                    true,
+	"iot":                    true,
+	"kinesis":                true,
+	"kinesisvideo":           true,
+	"kms":                    true,
+	"lambda":                 true,
+	"organizations":          true,
+	"ram":                    true,
+	"rds":                    true,
+	"redshift":               true,
+	"resourcegroups":         true,
+	"route53":                true,
+	"s3":                     true,
+	"s3control":              true,
+	"sagemaker":              true,
+	"secretsmanager":         true,
+	"ses":                    true,
+	"sns":                    true,
+	"sqs":                    true,
+	"ssm":                    true,
+	"stepfunctions":          true,
+	"sts":                    true,
+}
+
+// Supports returns true if moto_server implements the service
+func (m *Moto) Supports(service string) bool {
+	return motoServices[service]
+}
+
+// resolveServices returns the custom endpoints for the Terraform files
+// in dir, either the configured services or the detected services
+func (j *job) resolveServices(cfg *Config, dir string) ([]string, error) {
+	if len(cfg.Services) > 0 {
+		return cfg.Services, nil
+	}
+
+	detected, err := detectServices(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// terragrunt keeps the configuration of a remote source inside of
+	// its cache, so finding no resources at all is as unknown as finding
+	// an unknown resource, otherwise every call would reach AWS
+	if detected.Types == 0 {
+		j.printf("WARNING: no aws resource types found, using all endpoints\n")
+		return defaultServices, nil
+	}
+
+	// without knowing every service the job may call,
+	// fall back to providing every known endpoint
+	if len(detected.Unknown) > 0 {
+		j.printf("WARNING: unknown service for resource types: %s, using all endpoints\n", strings.Join(detected.Unknown, ", "))
+		return defaultServices, nil
+	}
+
+	if s, ok := j.emulator.(ServiceSupporter); ok {
+		var unsupported []string
+		for _, service := range detected.Services {
+			if !s.Supports(service) {
+				unsupported = append(unsupported, service)
+			}
+		}
+		if len(unsupported) > 0 {
+			j.printf("WARNING: services not implemented by the emulator: %s\n", strings.Join(unsupported, ", "))
+		}
+	}
+
+	return detected.Services, nil
+}
@@ -0,0 +1,140 @@
+package tester
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestServiceFor(t *testing.T) {
+	tt := map[string]struct {
+		resource string
+		expected string
+		found    bool
+	}{
+		"kms":         {resource: "aws_kms_key", expected: "kms", found: true},
+		"logs":        {resource: "aws_cloudwatch_log_group", expected: "cloudwatchlogs", found: true},
+		"events":      {resource: "aws_cloudwatch_event_rule", expected: "cloudwatchevents", found: true},
+		"longest":     {resource: "aws_route53_resolver_endpoint", expected: "route53resolver", found: true},
+		"route_table": {resource: "aws_route_table", expected: "ec2", found: true},
+		"s3control":   {resource: "aws_s3_account_public_access_block", expected: "s3control", found: true},
+		"unknown":     {resource: "aws_unknown_thing", found: false},
+
+		"launch_configuration": {resource: "aws_launch_configuration", expected: "autoscaling", found: true},
+		"launch_template":      {resource: "aws_launch_template", expected: "ec2", found: true},
+		"identity_provider":    {resource: "aws_cognito_identity_provider", expected: "cognitoidp", found: true},
+		"identity_pool":        {resource: "aws_cognito_identity_pool", expected: "cognitoidentity", found: true},
+		"access_point":         {resource: "aws_s3_access_point", expected: "s3control", found: true},
+		"bucket":               {resource: "aws_s3_bucket", expected: "s3", found: true},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual, found := serviceFor(tc.resource)
+			if found != tc.found || actual != tc.expected {
+				t.Errorf("service invalid, expected: %s (%t), got: %s (%t)", tc.expected, tc.found, actual, found)
+			}
+		})
+	}
+}
+
+func TestDetectServices(t *testing.T) {
+	dir, err := ioutil.TempDir("", "services")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"main.tf": `
+data "aws_iam_policy_document" "key" {}
+resource "aws_kms_key" "key" {}
+module "bucket" {
+  source = "./bucket"
+}
+`,
+		"bucket/main.tf": `resource "aws_s3_bucket" "bucket" {}
+  resource "aws_unknown_thing" "x" {}`,
+		".terraform/modules/modules.json": `{"Modules":[{"Key":"","Source":"","Dir":"."},{"Key":"bucket","Source":"./bucket","Dir":"bucket"}]}`,
+	}
+	for f, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f))
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		err = ioutil.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("failed to write file: %s -> %v", f, err)
+		}
+	}
+
+	detected, err := detectServices(dir)
+	if err != nil {
+		t.Fatalf("failed to detect services: %v", err)
+	}
+	expected := &detectedServices{
+		Services: []string{"kms", "s3", "sts"},
+		Unknown:  []string{"aws_unknown_thing"},
+		Types:    4,
+	}
+	if !reflect.DeepEqual(detected, expected) {
+		t.Errorf("services invalid, expected: %v, got: %v", expected, detected)
+	}
+}
+
+func TestResolveServices(t *testing.T) {
+	dir, err := ioutil.TempDir("", "services")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "aws_sns_topic" "t" {}`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	j := &job{Name: "services", Path: dir, emulator: NewMoto()}
+	actual, err := j.resolveServices(&Config{}, j.dir())
+	if err != nil {
+		t.Fatalf("failed to resolve services: %v", err)
+	}
+	if !reflect.DeepEqual(actual, []string{"sns", "sts"}) {
+		t.Errorf("services invalid, expected: [sns sts], got: %v", actual)
+	}
+
+	actual, err = j.resolveServices(&Config{Services: []string{"s3"}}, j.dir())
+	if err != nil {
+		t.Fatalf("failed to resolve services: %v", err)
+	}
+	if !reflect.DeepEqual(actual, []string{"s3"}) {
+		t.Errorf("configured services must be used as-is, got: %v", actual)
+	}
+}
+
+func TestResolveServicesNoneDetected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "services")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a terragrunt job with a remote source has no .tf files of its own
+	err = ioutil.WriteFile(filepath.Join(dir, "terragrunt.hcl"), []byte(`terraform { source = "git::https://example.com/bucket.git" }`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	j := &job{Name: "services", Path: dir, Stdout: ioutil.Discard, Stderr: ioutil.Discard}
+	actual, err := j.resolveServices(&Config{}, j.dir())
+	if err != nil {
+		t.Fatalf("failed to resolve services: %v", err)
+	}
+	if !reflect.DeepEqual(actual, defaultServices) {
+		t.Errorf("services invalid, expected: %v, got: %v", defaultServices, actual)
+	}
+}
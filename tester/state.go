@@ -0,0 +1,152 @@
+package tester
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// the names of the files that the state of the emulator
+// is written to when a job fails
+const (
+	stateFile        = "emulator-state.json"
+	emulatorDataFile = "emulator-data.json"
+)
+
+// stateTimeout limits each request for the state of the emulator
+const stateTimeout = 30 * time.Second
+
+// StateDumper is implemented by emulators that are able to export
+// every resource they contain, the state is written to the artifacts
+// directory of a job that fails
+type StateDumper interface {
+	DumpState() ([]byte, error)
+}
+
+// DumpState returns the models of every moto backend
+func (m *Moto) DumpState() ([]byte, error) {
+	if m.bin == nil {
+		return nil, errors.New("moto_server has not been started")
+	}
+	return getURL(m.Endpoint() + motoHealthPath)
+}
+
+// getURL returns the body of a successful GET request to rawurl
+func getURL(rawurl string) ([]byte, error) {
+	client := &http.Client{Timeout: stateTimeout}
+	resp, err := client.Get(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request: %s -> %v", rawurl, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %s -> %v", rawurl, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("failed to request: %s -> %s", rawurl, resp.Status)
+	}
+	return data, nil
+}
+
+// emulatorState is the JSON encoded content of stateFile
+type emulatorState struct {
+	Endpoint  string            `json:"endpoint"`
+	Resources inventory         `json:"resources"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+// snapshot lists the resources of each service in the emulator, unlike
+// takeInventory the services that fail to list are recorded rather
+// than stopping the snapshot
+func snapshot(endpoint string, env, services []string) (*emulatorState, error) {
+	sess, err := newSession(endpoint, env)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &emulatorState{
+		Endpoint:  endpoint,
+		Resources: make(inventory),
+		Errors:    make(map[string]string),
+	}
+	for _, s := range listedServices(services) {
+		ids, err := listers[s](sess)
+		if err != nil {
+			state.Errors[s] = err.Error()
+			continue
+		}
+		sort.Strings(ids)
+		state.Resources[s] = ids
+	}
+	return state, nil
+}
+
+// stateServices returns the services that are included in the
+// snapshot, Config.Services when it is set, otherwise the services
+// detected for the job
+func (j *job) stateServices(cfg *Config) []string {
+	if len(cfg.Services) > 0 {
+		return cfg.Services
+	}
+	return j.Services
+}
+
+// dumpState writes the resources in the emulator to the artifacts
+// directory of the job so a failure is able to be debugged after the
+// emulator is gone, failing to do so does not change the job result
+func (j *job) dumpState(cfg *Config, endpoint string) {
+	if len(endpoint) == 0 || len(j.LogDir) == 0 {
+		return
+	}
+
+	err := j.writeState(cfg, endpoint)
+	if err != nil {
+		j.printf("failed to dump emulator state: %v\n", err)
+	}
+}
+
+func (j *job) writeState(cfg *Config, endpoint string) error {
+	j.mu.Lock()
+	env := append([]string{}, j.Env...)
+	j.mu.Unlock()
+
+	state, err := snapshot(endpoint, env, j.stateServices(cfg))
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode emulator state: %v", err)
+	}
+
+	path := filepath.Join(j.LogDir, stateFile)
+	err = writeArtifact(path, data)
+	if err != nil {
+		return err
+	}
+	j.StateDump = path
+	j.printf("emulator state written to: %s\n", path)
+
+	d, ok := j.emulator.(StateDumper)
+	if !ok {
+		return nil
+	}
+	data, err = d.DumpState()
+	if err != nil {
+		return err
+	}
+	path = filepath.Join(j.LogDir, emulatorDataFile)
+	err = writeArtifact(path, data)
+	if err != nil {
+		return err
+	}
+	j.printf("emulator data written to: %s\n", path)
+	return nil
+}
@@ -0,0 +1,125 @@
+package tester
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != motoHealthPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"s3": {}}`))
+	}))
+	defer srv.Close()
+
+	tt := map[string]struct {
+		path     string
+		expected string
+		err      bool
+	}{
+		"found": {
+			path:     motoHealthPath,
+			expected: `{"s3": {}}`,
+		},
+		"not found": {
+			path: "/missing",
+			err:  true,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			data, err := getURL(srv.URL + tc.path)
+			if tc.err != (err != nil) {
+				t.Fatalf("error invalid, expected error: %t, got: %v", tc.err, err)
+			}
+			if string(data) != tc.expected {
+				t.Errorf("data invalid, expected: %s, got: %s", tc.expected, data)
+			}
+		})
+	}
+}
+
+// stateDumper is an External emulator that is able to dump its state
+type stateDumper struct {
+	External
+}
+
+func (d *stateDumper) DumpState() ([]byte, error) {
+	return getURL(d.URL + motoHealthPath)
+}
+
+func TestDumpState(t *testing.T) {
+	// every SDK request fails so the errors are recorded
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == motoHealthPath {
+			_, _ = w.Write([]byte(`{"iam": {"Role": []}}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := newJob("state", dir, dir, "", []string{"AWS_REGION=us-east-1"}, &JobConfig{})
+	j.Stdout, j.Stderr = ioutil.Discard, ioutil.Discard
+	j.LogDir = filepath.Join(dir, "logs")
+	j.Services = []string{"s3", "ec2"}
+	j.emulator = &stateDumper{External{URL: srv.URL}}
+
+	j.dumpState(&Config{}, srv.URL)
+
+	if j.StateDump != filepath.Join(j.LogDir, stateFile) {
+		t.Fatalf("state dump invalid, expected: %s, got: %s", filepath.Join(j.LogDir, stateFile), j.StateDump)
+	}
+	data, err := ioutil.ReadFile(j.StateDump)
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+	state := &emulatorState{}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		t.Fatalf("failed to parse state: %v", err)
+	}
+	if state.Endpoint != srv.URL {
+		t.Errorf("endpoint invalid, expected: %s, got: %s", srv.URL, state.Endpoint)
+	}
+	if _, ok := state.Errors["s3"]; !ok || len(state.Errors) != 1 {
+		t.Errorf("errors invalid, expected only s3, got: %v", state.Errors)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(j.LogDir, emulatorDataFile))
+	if err != nil {
+		t.Fatalf("failed to read emulator data: %v", err)
+	}
+	if string(data) != `{"iam": {"Role": []}}` {
+		t.Errorf("emulator data invalid, got: %s", data)
+	}
+}
+
+func TestStateServices(t *testing.T) {
+	j := &job{Services: []string{"s3"}}
+
+	actual := j.stateServices(&Config{Services: []string{"iam"}})
+	if len(actual) != 1 || actual[0] != "iam" {
+		t.Errorf("services invalid, expected: [iam], got: %v", actual)
+	}
+	actual = j.stateServices(&Config{})
+	if len(actual) != 1 || actual[0] != "s3" {
+		t.Errorf("services invalid, expected: [s3], got: %v", actual)
+	}
+}
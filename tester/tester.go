@@ -3,21 +3,19 @@ package tester
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"html/template"
 	"io"
 	"io/ioutil"
-	"net"
-	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
-	"runtime"
-	"strconv"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"text/template"
 	"time"
 )
 
@@ -33,23 +31,205 @@ type Config struct {
 
 	// Services is a list of Terraform AWS Provider custom endpoints
 	// https://www.terraform.io/docs/providers/aws/guides/custom-service-endpoints.html
-	// By default all custom endpoints are statically provided but this
-	// provides an option for explicitly listing them
+	// By default the custom endpoints are detected from the aws resource
+	// types used by each job, falling back to all known endpoints when a
+	// resource type is not recognized, but this provides an option for
+	// explicitly listing them
 	Services []string
 
 	// JobsPerCPU is the number of jobs that will be executed in parallel
 	// per CPU. If it is not set, it will default to 1
 	JobsPerCPU int
 
+	// MaxJobs is the number of job slots shared by the jobs running in
+	// parallel, each job uses the slots of its JobConfig weight. If it
+	// is set, it replaces JobsPerCPU
+	MaxJobs int
+
+	// DurationsFile records the duration of each job, the longest jobs
+	// of previous runs are started first. If it is not set, it will
+	// default to a file for Dir inside of the user cache directory
+	DurationsFile string
+
+	// Emulator returns the AWS API emulator used by each job. If it is
+	// not set, it will default to NewMoto
+	Emulator EmulatorFactory
+
+	// PoolSize is the number of emulators that are started once and
+	// shared between jobs, the state of an emulator is reset before
+	// each job uses it. If it is not set, each job starts its own emulator
+	PoolSize int
+
+	// Destroy runs terraform destroy after the test and fails the job
+	// if any resources of the services in use are left in the emulator
+	Destroy bool
+
+	// PlanOnly stops each job after terraform plan instead of starting
+	// an emulator and applying it, the JSON plan is provided to the test
+	// using TF_PLAN_FILE and is able to be checked using package tfplan.
+	// Any data source that calls AWS fails in this mode
+	PlanOnly bool
+
+	// CheckIdempotency plans each job again after it has been applied
+	// and fails the job if the plan contains any changes, for example
+	// from a policy that is reordered or tags that drift
+	CheckIdempotency bool
+
+	// Retries is the number of times a failed job is run again from a
+	// fresh workspace and emulator, jobs that only pass after a retry are
+	// reported as FLAKY along with the error of every failed attempt
+	Retries int
+
+	// Watch keeps the emulator and initialized workspace of every job
+	// after it runs, the .tf and _test.go files of each job and its
+	// fixtures are polled for changes and a changed job is applied and
+	// tested again after its emulator is reset, or after a new emulator
+	// is started if it has exited. Run only returns once it is
+	// interrupted, the emulator must implement Resetter and Retries
+	// are ignored
+	Watch bool
+
+	// WatchInterval is how often the files of each job are polled in
+	// watch mode. If it is not set, it will default to 1 second
+	WatchInterval time.Duration
+
+	// Cassettes records the AWS API requests that go test makes into a
+	// cassette file for each job using CassetteRecord, CassetteReplay
+	// runs go test against the recorded responses and outputs without
+	// starting an emulator or running terraform or any hooks
+	Cassettes CassetteMode
+
+	// CassetteDir is the directory of the cassette files, which are named
+	// after each job. If it is not set, it will default to the cassettes
+	// directory inside of Dir
+	CassetteDir string
+
+	// Coverage runs go test of each job with -coverprofile, the profiles
+	// of every job are merged into CoverageFile and summarized along
+	// with the job results
+	Coverage bool
+
+	// CoverPkg are the packages that coverage is recorded for using
+	// -coverpkg, for example: github.com/GSA/grace-tftest/aws/...
+	// If it is not set, only the package of the test is covered
+	CoverPkg []string
+
+	// CoverageFile is the merged coverage profile of every job. If it
+	// is not set, it will default to coverage.out inside of ArtifactsDir
+	// and a temporary ArtifactsDir is kept after the run
+	CoverageFile string
+
+	// ResourceCoverage records the resources that the assertions of go
+	// test select using the aws packages, once the test passes each
+	// managed resource in the state of the job that no assertion selected
+	// is reported along with the job results
+	ResourceCoverage bool
+
+	// ResourceThreshold is the percentage of the managed resources of
+	// each job that must be selected by an assertion, for example: 80.
+	// Jobs below it fail. It requires ResourceCoverage and is ignored
+	// when it is not set
+	ResourceThreshold float64
+
+	// Timeouts limits the duration of each job phase and of the job
+	// as a whole. If it is not set, jobs are allowed to run forever
+	Timeouts Timeouts
+
+	// Include is a list of glob patterns matched against job names,
+	// if it is set, only jobs matching at least one pattern are run
+	Include []string
+
+	// Exclude is a list of glob patterns matched against job names,
+	// jobs matching any pattern are skipped
+	Exclude []string
+
+	// Tags limits the jobs that are run to jobs declaring at least one
+	// of the tags in their JobConfigFile
+	Tags []string
+
+	// ExcludeTags skips jobs declaring any of the tags in their JobConfigFile
+	ExcludeTags []string
+
+	// Shard runs a stable subset of the jobs in the form index/count,
+	// for example: 2/5 runs the second of five shards. If it is not set,
+	// it will default to the value of TFTEST_SHARD
+	Shard string
+
+	// WorkspaceDir is the directory that each job's temporary copy of
+	// its test directory is created in, terraform runs from the copy so
+	// the test directory is never modified. If it is not set, it will
+	// default to the system temporary directory
+	WorkspaceDir string
+
+	// KeepArtifacts preserves the workspace and state of every failed
+	// job, their location is printed with the job results
+	KeepArtifacts bool
+
+	// ArtifactsDir is the directory that the emulator, terraform, and
+	// test log files of each job are written to, inside of a directory
+	// named after the job. If it is not set, a temporary directory is
+	// used that is removed when every job succeeds
+	ArtifactsDir string
+
+	// Stream prints the output of every job prefixed with the job name
+	// instead of the live status view, the output is still written to
+	// the log files of each job
+	Stream bool
+
+	// PluginCacheDir is the terraform plugin cache shared by every job,
+	// it is warmed by running terraform init once before any job starts.
+	// If it is not set, it will default to tftest/plugins inside of the
+	// user cache directory
+	PluginCacheDir string
+
+	// ProviderMirror is a directory using the terraform filesystem mirror
+	// layout, if it is set, providers are only installed from the mirror
+	// so jobs are able to run without network access
+	ProviderMirror string
+
+	// Driver runs the infrastructure as code tool of each job, the
+	// built-in drivers are Terraform, Tofu, and Terragrunt. If it is
+	// not set, it will default to terraform from the PATH
+	Driver Driver
+
+	// Hooks are shell commands and Go funcs that are run at points of
+	// every job with the environment of the job, for example: seeding
+	// the emulator before apply or running tflint
+	Hooks []Hook
+
+	// Provider configures the generated provider.tf, it is replaced by
+	// the provider setting of a job's JobConfigFile when one is present
+	Provider *ProviderConfig
+
 	// interally used to store parsed ENV variables
 	vars []string
+
+	// internally used to share emulators between jobs
+	pool *pool
+
+	// internally used to store the path of the generated CLI config
+	cliConfig string
+
+	// internally used to remove the temporary artifacts directory
+	removeArtifacts bool
+
+	// internally used to re-run jobs in watch mode
+	watch *watcher
+
+	// internally used to stop retrying jobs after an interrupt
+	interrupted int32
+
+	// internally used as the output of every job, the output
+	// is discarded unless Stream is set
+	stdout io.Writer
+	stderr io.Writer
 }
 
 // Run enumerates over each subfolder in the provided directory
 // stubs out a provider.tf with a fully populated aws provider with the
 // provided services or by default it will add all known services then
-// executes the first file ending in _test.go against moto_server on the
-// first available port
+// executes the first file ending in _test.go against the configured
+// emulator (moto_server on the first available port by default)
 func Run(cfg *Config) error {
 	// validate and update configuration
 	err := prepareConfig(cfg)
@@ -57,49 +237,147 @@ func Run(cfg *Config) error {
 		return err
 	}
 
+	err = prepareArtifacts(cfg)
+	if err != nil {
+		return err
+	}
+
 	// create job objects from sub-directories
 	jobs, err := buildJobs(cfg.Dir, cfg.vars)
 	if err != nil {
 		return err
 	}
 
+	// apply dependencies and fixtures before each job
+	err = resolveFixtures(jobs)
+	if err != nil {
+		return err
+	}
+
+	// skip the jobs that were not selected
+	err = selectJobs(cfg, jobs)
+	if err != nil {
+		return err
+	}
+
+	err = preparePlugins(cfg)
+	if err != nil {
+		return err
+	}
+
+	// terraform and the emulator are not used when replaying
+	version := "none, replaying cassettes"
+	if cfg.Cassettes != CassetteReplay {
+		// the version is only reported, so a failure is not fatal
+		version, err = detectVersion(cfg)
+		if err != nil {
+			fmt.Printf("failed to detect %s version: %v\n", cfg.Driver.Name(), err)
+			version = cfg.Driver.Name() + " (unknown version)"
+		}
+
+		// a failed warm up is not fatal, the jobs
+		// will report the actual terraform errors
+		err = warmPluginCache(cfg)
+		if err != nil {
+			fmt.Printf("failed to warm plugin cache: %v\n", err)
+		}
+	}
+
+	if cfg.PoolSize > 0 && cfg.Cassettes != CassetteReplay {
+		cfg.pool, err = newPool(cfg, cfg.PoolSize)
+		if err != nil {
+			return err
+		}
+	}
+
 	sigch := make(chan os.Signal, 1)
 	done := make(chan struct{}, 1)
 
 	// listen for Interrupt or Termination signals
 	signal.Notify(sigch, syscall.SIGINT, syscall.SIGTERM)
 
-	// kick off jobs in a new go routine
-	go runJobs(done, cfg, jobs)
+	// show the status of each job unless their
+	// output is being streamed instead
+	var view *progress
+	if !cfg.Stream && !cfg.Watch {
+		view = newProgress(os.Stdout, isTerminal(os.Stdout), jobs)
+		view.start()
+	}
+
+	// an unreadable file only affects the order of the jobs
+	recorded, err := readDurations(cfg.DurationsFile)
+	if err != nil {
+		fmt.Printf("failed to read job durations: %v\n", err)
+		recorded = durations{}
+	}
+
+	// kick off jobs in a new go routine, in watch mode
+	// the jobs keep running until an interrupt
+	if cfg.Watch {
+		cfg.watch = newWatcher(cfg, os.Stdout)
+		go cfg.watch.start(scheduleJobs(jobs, recorded))
+	} else {
+		go runJobs(done, cfg, scheduleJobs(jobs, recorded))
+	}
 
 	// watch for interrupt signals simultaneously
 	go func() {
 		sig := <-sigch
 		fmt.Printf("interrupt received: %v\n", sig)
+		atomic.StoreInt32(&cfg.interrupted, 1)
 		done <- struct{}{} // trigger shutdown of app disrupting jobs
 	}()
 
 	<-done // wait for all jobs to complete
+	if view != nil {
+		view.close()
+	}
+
+	recorded.record(jobs)
+	err = recorded.write(cfg.DurationsFile)
+	if err != nil {
+		fmt.Printf("failed to record job durations: %v\n", err)
+	}
+
+	if cfg.watch != nil {
+		cfg.watch.close()
+	}
 
 	// enumerate for cleanup separately so any lingering printing
 	// caused by the interrupt or killing the processes is printed
 	// prior to the job report
 	for _, j := range jobs {
-		j.cleanup()
+		j.cleanup(cfg.KeepArtifacts)
+	}
+	if cfg.pool != nil {
+		cfg.pool.close()
+	}
+	if len(cfg.cliConfig) > 0 {
+		err = ignoreNotExistsErr(os.Remove(cfg.cliConfig))
+		if err != nil {
+			fmt.Printf("failed to cleanup: %s -> %v\n", cfg.cliConfig, err)
+		}
 	}
 
 	// We have either completed all jobs or
 	// an interrupt signal has been received
 	// print their final status output
-	fmt.Printf("\n\n\n\n===== Job Results =====\n")
-	var failed bool
-	for _, j := range jobs {
-		if j.Err != nil {
-			failed = true
-			fmt.Printf("%-20s%-15s%v\n", j.Name, "FAILED", j.Err)
-			continue
+	failed := report(os.Stdout, version, jobs)
+	if cfg.Coverage {
+		summary, err := mergeCoverage(cfg, jobs)
+		if err != nil {
+			fmt.Printf("failed to merge coverage: %v\n", err)
+		} else {
+			reportCoverage(os.Stdout, summary)
+		}
+	}
+
+	// the logs of successful runs are not worth keeping
+	if !failed && cfg.removeArtifacts {
+		err = os.RemoveAll(cfg.ArtifactsDir)
+		if err != nil {
+			fmt.Printf("failed to cleanup: %s -> %v\n", cfg.ArtifactsDir, err)
 		}
-		fmt.Printf("%-20s%-15s\n", j.Name, "SUCCESS")
 	}
 
 	// we need to exit non-zero if any job failed
@@ -110,35 +388,42 @@ func Run(cfg *Config) error {
 }
 
 func runJobs(done chan struct{}, cfg *Config, jobs []*job) {
-	maxProcs := runtime.NumCPU() * cfg.JobsPerCPU
-	throttle := make(chan struct{}, maxProcs)
+	size := capacity(cfg)
+	free := newSlots(size)
 	wg := &sync.WaitGroup{}
 
 	for _, j := range jobs {
 		j := j
-		// store an empty struct (zero memory alloc)
-		// into the free capacity for throttle for each
-		// job that we execute, this will block when we
-		// reach capacity until a job is completed
-		throttle <- struct{}{}
+		if j.Skipped {
+			continue
+		}
+
+		// take the slots of the job's weight, this
+		// will block when there are not enough free
+		// slots until running jobs have completed,
+		// jobs are started strictly in order so heavy
+		// jobs are not starved by lighter ones
+		weight := j.weight(size)
+		free.acquire(weight)
 
 		// waitgroup is needed to prevent the last X
 		// jobs from being prematurely killed where X
-		// is the capacity of the throttle channel
+		// is the number of jobs still running
 		wg.Add(1)
 
 		go func() {
 			// run the 'j' job and store the error result
-			j.Err = j.run(cfg.Services)
-			// free one element in the channel
-			<-throttle
+			j.begin()
+			j.finish(j.runAttempts(cfg))
+			// free the slots of the job
+			free.release(weight)
 			// decrement waitgroup by one
 			wg.Done()
 		}()
 	}
 
 	// block until all job go routines have completed
-	// this is only hit when we have more throttle capacity
+	// this is only hit when we have more free slots
 	// than we do jobs remaining
 	wg.Wait()
 
@@ -160,6 +445,54 @@ func prepareConfig(cfg *Config) error {
 		cfg.JobsPerCPU = 1
 	}
 
+	if cfg.Emulator == nil {
+		cfg.Emulator = defaultEmulator
+	}
+
+	if cfg.Driver == nil {
+		cfg.Driver = defaultDriver
+	}
+
+	if cfg.Watch && cfg.PoolSize > 0 {
+		return errors.New("watch mode is not able to share emulators using PoolSize")
+	}
+
+	if cfg.ResourceThreshold < 0 || cfg.ResourceThreshold > 100 {
+		return fmt.Errorf("invalid resource threshold: %v, it must be between 0 and 100", cfg.ResourceThreshold)
+	}
+
+	err := validateHooks(cfg.Hooks)
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Shard) == 0 {
+		cfg.Shard = os.Getenv(ShardEnv)
+	}
+
+	if len(cfg.PluginCacheDir) == 0 {
+		cfg.PluginCacheDir = defaultPluginCacheDir()
+	}
+
+	switch cfg.Cassettes {
+	case CassetteOff, CassetteRecord, CassetteReplay:
+	default:
+		return fmt.Errorf("invalid cassette mode: %q", cfg.Cassettes)
+	}
+
+	if len(cfg.CassetteDir) == 0 {
+		cfg.CassetteDir = filepath.Join(cfg.Dir, "cassettes")
+	}
+
+	if len(cfg.DurationsFile) == 0 {
+		cfg.DurationsFile = defaultDurationsFile(cfg.Dir)
+	}
+
+	cfg.stdout, cfg.stderr = ioutil.Discard, ioutil.Discard
+	if cfg.Stream {
+		cfg.stdout, cfg.stderr = os.Stdout, os.Stderr
+	}
+
 	cfg.vars = mapToKeyValueSlice(mapMerge(
 		map[string]string{
 			"AWS_ACCESS_KEY_ID":     "mock_access_key",
@@ -178,168 +511,469 @@ type job struct {
 	Path         string
 	TestFile     string
 	ProviderFile string
+	OutputsFile  string
+	PlanFile     string
+	StateFile    string
+	WorkDir      string
+	Artifacts    string
+	LogDir       string
+	StateDump    string
+	CoverProfile string
+	Ledger       string
+	VarArgs      []string
+	Fixtures     []fixture
+	Services     []string
 	Env          []string
 	Err          error
+	Attempts     []error
 	Stderr       io.Writer
 	Stdout       io.Writer
-	Processes    []*exec.Cmd
+	Processes    []*Process
+	Phase        string
+	Skipped      bool
+	Config       *JobConfig
+	Started      time.Time
+	Finished     time.Time
+	Resources    *resourceCoverage
+
+	mu       sync.Mutex
+	tail     *tailBuffer
+	logs     *logFiles
+	emulator Emulator
+	tool     Driver
+
+	// coverPkg is passed to go test using -coverpkg
+	coverPkg string
+
+	// initialized is set once terraform init succeeds
+	initialized bool
 }
 
-func (j *job) run(services []string) error {
-	port, err := getPort()
+func (j *job) run(cfg *Config) (err error) {
+	j.setLogs(cfg)
+	j.setCoverage(cfg)
+	j.setLedger(cfg)
+	j.tool = cfg.Driver
+
+	ctx, cancel := withTimeout(context.Background(), cfg.Timeouts.Job)
+	defer cancel()
+
+	// the emulator is stopped after its state has been
+	// dumped and the failure hooks have inspected it
+	var ready string
+	stopEmulator := func() {}
+	defer func() {
+		stopEmulator()
+	}()
+	defer func() {
+		if err != nil {
+			j.dumpState(cfg, ready)
+			err = j.runFailureHooks(cfg, err)
+		}
+	}()
+
+	err = j.prepareWorkspace(cfg.WorkspaceDir)
 	if err != nil {
 		return err
 	}
 
-	cleanup, err := j.startMoto(port)
+	if j.planOnly(cfg) {
+		return j.runPlanOnly(ctx, cfg)
+	}
+
+	if j.cassetteMode(cfg) == CassetteReplay {
+		return j.runReplay(ctx, cfg)
+	}
+
+	endpoint, cleanup, err := j.setupEmulator(ctx, cfg)
+	if cleanup != nil {
+		stopEmulator, ready = cleanup, endpoint
+	}
 	if err != nil {
 		return err
 	}
-	defer cleanup()
 
-	err = writeProvider(j.ProviderFile, services, port)
+	return j.runSteps(ctx, cfg, endpoint, true)
+}
+
+// setupEmulator starts the emulator of the job along with the hooks
+// around it, the emulator is returned along with the error of the
+// AfterEmulator hooks so its state is able to be inspected
+func (j *job) setupEmulator(ctx context.Context, cfg *Config) (string, func(), error) {
+	err := j.runHooks(ctx, cfg, BeforeEmulator, nil)
 	if err != nil {
+		return "", nil, err
+	}
+
+	// the phase is abandoned when it times out, an emulator that
+	// is ready afterwards is stopped since nothing else would
+	var (
+		mu        sync.Mutex
+		abandoned bool
+		emu       Emulator
+		endpoint  string
+		cleanup   func()
+	)
+	err = j.runPhase(ctx, phaseEmulator, cfg.Timeouts.Emulator, func(ctx context.Context) error {
+		var (
+			e   Emulator
+			url string
+			c   func()
+			err error
+		)
+		if cfg.pool != nil {
+			e, url, c, err = j.acquireEmulator(ctx, cfg.pool)
+		} else {
+			e = cfg.Emulator()
+			url, c, err = j.startEmulator(e)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if abandoned {
+			if c != nil {
+				c()
+			}
+			return err
+		}
+		emu, endpoint, cleanup = e, url, c
 		return err
+	})
+
+	mu.Lock()
+	abandoned = true
+	mu.Unlock()
+	if err != nil {
+		// the emulator may have been ready just as time ran out
+		if cleanup != nil {
+			cleanup()
+		}
+		return "", nil, err
 	}
 
-	err = j.runTerraform()
+	j.emulator = emu
+	err = j.setEndpointEnv(endpoint)
 	if err != nil {
-		return err
+		cleanup()
+		return "", nil, err
 	}
 
-	return j.runTest()
+	err = j.runHooks(ctx, cfg, AfterEmulator, nil)
+	return endpoint, cleanup, err
 }
 
-const urlFmt = "http://localhost:%d"
+// runSteps applies and tests the job against the emulator at endpoint,
+// terraform init is skipped unless init is set
+func (j *job) runSteps(ctx context.Context, cfg *Config, endpoint string, init bool) error {
+	var err error
+	if len(j.Fixtures) > 0 {
+		err = j.runPhase(ctx, phaseFixtures, cfg.Timeouts.Fixtures, func(ctx context.Context) error {
+			return j.applyFixtures(ctx, cfg, endpoint)
+		})
+		if err != nil {
+			return err
+		}
+	}
 
-func (j *job) startMoto(port int) (func(), error) {
-	// MOTO_PORT should be available as an ENV var to any
-	// process started for this job
-	j.Env = append(j.Env, "MOTO_PORT="+strconv.Itoa(port))
+	// the services in use are not known until terraform init
+	// has installed the modules, so only sts is provided until then
+	services := cfg.Services
+	if len(services) == 0 {
+		services = []string{"sts"}
+	}
+	err = writeProvider(j.ProviderFile, j.provider(cfg), services, endpoint, filepath.Base(j.StateFile))
+	if err != nil {
+		return err
+	}
 
-	moto, err := j.startProcess("moto_server", "-p", strconv.Itoa(port))
+	if init {
+		j.initialized = false
+		err = j.runPhase(ctx, phaseInit, cfg.Timeouts.Init, j.runInit)
+		if err != nil {
+			return err
+		}
+		j.initialized = true
+	}
+
+	j.Services, err = j.resolveServices(cfg, j.dir())
 	if err != nil {
-		return nil, err
+		return err
+	}
+	err = writeProvider(j.ProviderFile, j.provider(cfg), j.Services, endpoint, filepath.Base(j.StateFile))
+	if err != nil {
+		return err
 	}
-	fmt.Printf("[%s]: waiting for moto to start...\n", j.Name)
-	maxRetries := 20
-	for i := 0; i < maxRetries; i++ {
-		time.Sleep(1 * time.Second)
-		//we own all variables related to this url variable
-		url := fmt.Sprintf(urlFmt, port)
 
-		var resp *http.Response
-		//nolint: gosec
-		resp, err := http.Get(url)
-		if err == nil {
-			err = resp.Body.Close()
-			if err != nil {
-				fmt.Printf("[%s]: failed to close response body: %v", j.Name, err)
-			}
-			break
+	var before inventory
+	if cfg.Destroy {
+		before, err = j.inventory(endpoint, j.Services)
+		if err != nil {
+			return err
 		}
-		fmt.Printf("[%s]: waiting for moto to start (attempt %d/%d)\n", j.Name, i+1, maxRetries)
 	}
 
-	return func() {
-		// if the process has already exited there is no point in
-		// continuing, so return to the caller
-		if moto.ProcessState != nil &&
-			moto.ProcessState.Exited() {
-			return
+	err = j.runHooks(ctx, cfg, BeforeApply, nil)
+	if err != nil {
+		return err
+	}
+
+	err = j.runPhase(ctx, phaseApply, cfg.Timeouts.Apply, func(ctx context.Context) error {
+		err := j.runApply(ctx)
+		if err != nil {
+			return err
 		}
-		err = kill(moto)
+		return j.injectOutputs(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = j.runHooks(ctx, cfg, AfterApply, nil)
+	if err != nil {
+		return err
+	}
+
+	if cfg.CheckIdempotency {
+		err = j.runPhase(ctx, phasePlan, cfg.Timeouts.Plan, j.checkIdempotency)
 		if err != nil {
-			fmt.Printf("[%s]: failed to terminate process: %v", j.Name, err)
+			return err
 		}
-	}, nil
-}
+	}
+
+	err = j.runHooks(ctx, cfg, BeforeTest, nil)
+	if err != nil {
+		return err
+	}
+
+	test := j.runTest
+	if j.cassetteMode(cfg) == CassetteRecord {
+		test = func(ctx context.Context) error {
+			return j.runRecorded(ctx, cfg, endpoint)
+		}
+	}
+	err = j.runPhase(ctx, phaseTest, cfg.Timeouts.Test, j.withResourceCoverage(cfg, test))
+	if err == nil {
+		err = j.runHooks(ctx, cfg, AfterTest, nil)
+	}
+	if !cfg.Destroy {
+		return err
+	}
 
-func (j *job) runTerraform() error {
-	init, err := j.startProcess("terraform", "init", "-no-color")
+	// destroy even when the test fails so leaks are still
+	// reported, but the test failure takes precedence
+	derr := j.runPhase(ctx, phaseDestroy, cfg.Timeouts.Destroy, func(ctx context.Context) error {
+		return j.runDestroy(ctx, endpoint, j.Services, before)
+	})
+	if err != nil && derr != nil {
+		return fmt.Errorf("%v; %v", err, derr)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to initialize terraform: %v", err)
+		return err
 	}
-	err = init.Wait()
+	return derr
+}
+
+// startEmulator starts emu and waits until it is ready
+func (j *job) startEmulator(emu Emulator) (string, func(), error) {
+	err := emu.Start(j.startProcess)
 	if err != nil {
-		return fmt.Errorf("failed to wait for terraform initialization: %v", err)
+		return "", nil, err
 	}
 
-	apply, err := j.startProcess("terraform", "apply", "-auto-approve", "-no-color")
+	cleanup := func() {
+		err := emu.Stop()
+		if err != nil {
+			j.printf("failed to stop emulator: %v\n", err)
+		}
+	}
+
+	j.printf("waiting for emulator to start...\n")
+	err = emu.Ready()
 	if err != nil {
-		return fmt.Errorf("failed to apply terraform: %v", err)
+		cleanup()
+		return "", nil, err
 	}
-	err = apply.Wait()
+
+	return emu.Endpoint(), cleanup, nil
+}
+
+// acquireEmulator waits for an emulator of the pool until ctx is done
+func (j *job) acquireEmulator(ctx context.Context, p *pool) (Emulator, string, func(), error) {
+	j.printf("waiting for a pooled emulator...\n")
+	m, err := p.acquire(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to wait for terraform apply: %v", err)
+		return nil, "", nil, err
 	}
-	return err
+
+	cleanup := func() {
+		p.release(m)
+	}
+	return m.Emulator, m.Endpoint(), cleanup, nil
 }
 
-func (j *job) runTest() error {
-	cmd, err := j.startProcess("go", "test", "-v", j.TestFile)
+func (j *job) setEndpointEnv(endpoint string) error {
+	port, err := endpointPort(endpoint)
 	if err != nil {
-		return fmt.Errorf("failed to execute test: %v", err)
+		return err
 	}
-	return cmd.Wait()
+
+	// MOTO_PORT and TFTEST_ENDPOINT should be available as
+	// ENV vars to any process started for this job
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Env = append(j.Env,
+		"MOTO_PORT="+port,
+		"TFTEST_ENDPOINT="+endpoint,
+	)
+	return nil
 }
 
-func (j *job) cleanup() {
-	j.cleanupProcesses()
+func (j *job) runInit(ctx context.Context) error {
+	return j.runStep(ctx, j.dir(), StepInit)
+}
 
-	err := retrier(100*time.Millisecond, 10, func() error {
-		return ignoreNotExistsErr(os.Remove(j.ProviderFile))
-	})
+func (j *job) runApply(ctx context.Context) error {
+	return j.runStep(ctx, j.dir(), StepApply, j.VarArgs...)
+}
+
+func (j *job) runDestroy(ctx context.Context, endpoint string, services []string, before inventory) error {
+	err := j.runStep(ctx, j.dir(), StepDestroy, j.VarArgs...)
 	if err != nil {
-		fmt.Printf("[%s]: failed to cleanup: %s -> %v\n", j.Name, j.ProviderFile, err)
+		return err
 	}
 
-	jobDir := filepath.Dir(j.ProviderFile)
-	tfstate := filepath.Join(jobDir, "terraform.tfstate")
-	tflock := filepath.Join(jobDir, ".terraform.tfstate.lock.info")
-	tfdir := filepath.Join(jobDir, ".terraform")
+	after, err := j.inventory(endpoint, services)
+	if err != nil {
+		return err
+	}
+	leaks := after.diff(before)
+	if len(leaks) > 0 {
+		return fmt.Errorf("resources leaked after destroy: %s", leaks)
+	}
+	return nil
+}
 
-	err = retrier(100*time.Millisecond, 10, func() error {
-		return ignoreNotExistsErr(os.Remove(tfstate))
-	})
+func (j *job) inventory(endpoint string, services []string) (inventory, error) {
+	sess, err := newSession(endpoint, j.Env)
 	if err != nil {
-		fmt.Printf("[%s]: failed to cleanup: %s -> %v\n", j.Name, tfstate, err)
+		return nil, err
 	}
+	return takeInventory(sess, services)
+}
 
-	err = retrier(100*time.Millisecond, 10, func() error {
-		return ignoreNotExistsErr(os.Remove(tflock))
-	})
+func (j *job) runTest(ctx context.Context) error {
+	// go test runs from the job directory
+	// so it resolves the go module
+	cmd, err := j.startProcessIn(ctx, j.Path, "go", j.testArgs()...)
 	if err != nil {
-		fmt.Printf("[%s]: failed to cleanup: %s -> %v\n", j.Name, tflock, err)
+		return fmt.Errorf("failed to execute test: %v", err)
 	}
+	return cmd.Wait()
+}
 
-	err = retrier(100*time.Millisecond, 10, func() error {
-		return ignoreNotExistsErr(os.RemoveAll(tfdir))
-	})
+func (j *job) cleanup(keep bool) {
+	j.cleanupProcesses()
+	err := j.logs.close()
 	if err != nil {
-		fmt.Printf("[%s]: failed to cleanup directory: %s -> %v\n", j.Name, tfdir, err)
+		fmt.Printf("[%s]: %v\n", j.Name, err)
 	}
+	j.removeWorkspace(keep)
 }
 
 func (j *job) cleanupProcesses() {
-	for _, p := range j.Processes {
-		if p.ProcessState != nil &&
-			p.ProcessState.Exited() {
+	j.killProcesses(0)
+}
+
+// processCount returns the number of processes started by the job
+func (j *job) processCount() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.Processes)
+}
+
+// runningProcesses returns the processes that are still
+// running from the process at index from onwards
+func (j *job) runningProcesses(from int) []*Process {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var running []*Process
+	for i := from; i < len(j.Processes); i++ {
+		if !j.Processes[i].Exited() {
+			running = append(running, j.Processes[i])
+		}
+	}
+	return running
+}
+
+// killProcesses kills the processes that are still running
+// from the process at index from onwards
+func (j *job) killProcesses(from int) {
+	j.mu.Lock()
+	var processes []*Process
+	if from < len(j.Processes) {
+		processes = append(processes, j.Processes[from:]...)
+	}
+	j.mu.Unlock()
+	for _, p := range processes {
+		if p.Exited() {
 			continue
 		}
-		err := kill(p)
+		err := kill(p.Cmd)
 		if err != nil {
-			fmt.Printf("[%s]: failed to kill process: %s -> %v\n", j.Name, p.Path, err)
+			j.printf("failed to kill process: %s -> %v\n", p.Path, err)
 		}
 	}
 }
 
-//nolint: errcheck
-func (j *job) startProcess(path string, args ...string) (*exec.Cmd, error) {
+// Process is a process started for a job, the output of the
+// process is read until it exits
+type Process struct {
+	*exec.Cmd
+
+	done   chan struct{}
+	err    error
+	stderr *tailBuffer
+}
+
+// Wait blocks until the process has exited and
+// all of its output has been read
+func (p *Process) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// Tail returns the last lines the process wrote to stderr
+func (p *Process) Tail() string {
+	return p.stderr.String()
+}
+
+// Exited returns true if the process is no longer running
+func (p *Process) Exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (j *job) startProcess(path string, args ...string) (*Process, error) {
+	return j.startProcessContext(context.Background(), path, args...)
+}
+
+// startProcessContext starts a process that is killed along with
+// all of its sub-processes once ctx is done
+func (j *job) startProcessContext(ctx context.Context, path string, args ...string) (*Process, error) {
+	return j.startProcessIn(ctx, j.dir(), path, args...)
+}
+
+// startProcessIn starts a process inside of dir
+func (j *job) startProcessIn(ctx context.Context, dir, path string, args ...string) (*Process, error) {
 	cmd := exec.Command(path, args...)
-	cmd.Dir = j.Path
+	cmd.Dir = dir
 	cmd.Env = os.Environ()
 	cmd.Env = append(cmd.Env, j.Env...)
+	setProcessGroup(cmd)
 
 	// grab the output pipes so we can prepend the job
 	// name before each line so it is easier to discern
@@ -358,24 +992,36 @@ func (j *job) startProcess(path string, args ...string) (*exec.Cmd, error) {
 		return nil, fmt.Errorf("failed to start process: %s -> %v", path, err)
 	}
 
+	p := &Process{Cmd: cmd, done: make(chan struct{}), stderr: &tailBuffer{}}
+
+	// the output is logged to the file of the phase
+	// that the process was started in
+	name := j.logName()
+
 	// keep up with what processes we have started
 	// so we can clean them up if we get an interrupt
 	// or if something goes badly
-	j.Processes = append(j.Processes, cmd)
+	j.mu.Lock()
+	j.Processes = append(j.Processes, p)
+	j.mu.Unlock()
 
 	go func() {
-		j.readOutput(stdoutP, stderrP)
-		// this will always fail as the process
-		// can only be fully terminated using the
-		// kill method, added nolint: errcheck to
-		// avoid linting errors related to this
-		cmd.Wait()
+		err := j.readOutput(p, name, stdoutP, stderrP)
+		if err != nil {
+			j.printf("failed to read output: %s -> %v\n", path, err)
+		}
+		// the pipes must be fully read before calling
+		// Wait, which is only ever called from here
+		p.err = cmd.Wait()
+		close(p.done)
 	}()
 
-	return cmd, nil
+	go watchContext(ctx, p)
+
+	return p, nil
 }
 
-func (j *job) readOutput(stdout, stderr io.Reader) error {
+func (j *job) readOutput(p *Process, name string, stdout, stderr io.Reader) error {
 	stdoutScanner := bufio.NewScanner(stdout)
 	stderrScanner := bufio.NewScanner(stderr)
 
@@ -384,8 +1030,8 @@ func (j *job) readOutput(stdout, stderr io.Reader) error {
 	// our wrapping func in a go routine for each pipe
 	wg := &sync.WaitGroup{}
 	wg.Add(2)
-	go j.wrapOutput(j.Stdout, stdoutScanner, wg)
-	go j.wrapOutput(j.Stderr, stderrScanner, wg)
+	go j.wrapOutput(j.Stdout, name, stdoutScanner, nil, wg)
+	go j.wrapOutput(j.Stderr, name, stderrScanner, p.stderr, wg)
 
 	// block until the process has stopped writing to the pipe
 	wg.Wait()
@@ -401,14 +1047,12 @@ func (j *job) readOutput(stdout, stderr io.Reader) error {
 	return nil
 }
 
-func (j *job) wrapOutput(out io.Writer, s *bufio.Scanner, wg *sync.WaitGroup) {
+func (j *job) wrapOutput(out io.Writer, name string, s *bufio.Scanner, tail *tailBuffer, wg *sync.WaitGroup) {
 	// Scan pulls one line from the pipe
 	// so we can wrap it with the job name
 	for s.Scan() {
-		_, err := fmt.Fprintf(out, "[%s]: %s\n", j.Name, s.Text())
-		if err != nil {
-			fmt.Printf("failed to Fprintf: %v\n", err)
-		}
+		tail.add(s.Text())
+		j.writeLine(out, name, s.Text())
 	}
 
 	// decrement the waitgroup
@@ -493,21 +1137,21 @@ func buildJobs(dir string, env []string) ([]*job, error) {
 			return nil
 		}
 
-		j := &job{
-			// use the last element of the path
-			// as the job name
-			Name:         filepath.Base(path),
-			RootPath:     base,
-			Path:         path,
-			TestFile:     matches[0], // use the first _test.go file
-			ProviderFile: filepath.Join(path, "provider.tf"),
-			Env:          env,
-			Err:          errors.New("job not executed"),
-			Stderr:       os.Stderr,
-			Stdout:       os.Stdout,
+		jc, err := readJobConfig(path)
+		if err != nil {
+			return err
 		}
 
-		jobs = append(jobs, j)
+		// use the last element of the path as the job
+		// name and the first _test.go file as the test
+		j := newJob(filepath.Base(path), base, path, matches[0], env, jc)
+
+		// a job with a matrix is replaced by one job per entry
+		expanded, err := expandMatrix(j, env)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, expanded...)
 
 		return nil
 	})
@@ -518,45 +1162,42 @@ func buildJobs(dir string, env []string) ([]*job, error) {
 	return jobs, nil
 }
 
-func getPort() (int, error) {
-	// open a connection on any free ephemeral port
-	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
-	if err != nil {
-		return 0, err
-	}
-
-	l, err := net.ListenTCP("tcp", addr)
-	if err != nil {
-		return 0, err
+// newJob returns a job for the test directory
+func newJob(name, root, path, testFile string, env []string, jc *JobConfig) *job {
+	return &job{
+		Name:     name,
+		RootPath: root,
+		Path:     path,
+		TestFile: testFile,
+		Env:      append([]string{}, env...),
+		Config:   jc,
+		Err:      errors.New("job not executed"),
+		Stderr:   os.Stderr,
+		Stdout:   os.Stdout,
+		tail:     &tailBuffer{},
 	}
-	defer l.Close()
+}
 
-	// grab the port used to open that connection
-	// and return it to the caller
-	if addr, ok := l.Addr().(*net.TCPAddr); ok {
-		return addr.Port, nil
+// provider returns the provider config of the job,
+// falling back to the provider config of Tester
+func (j *job) provider(cfg *Config) *ProviderConfig {
+	if j.Config != nil && j.Config.Provider != nil {
+		return j.Config.Provider
 	}
-
-	return 0, fmt.Errorf("failed to get an available port")
+	return cfg.Provider
 }
 
-// kill is used to terminate a process that has sub-processes
-func kill(cmd *exec.Cmd) error {
-	pid := strconv.Itoa(cmd.Process.Pid)
-	killers := map[string]*exec.Cmd{
-		"darwin":  exec.Command("kill", "-9", pid),
-		"linux":   exec.Command("kill", "-9", pid),
-		"windows": exec.Command("TASKKILL", "/T", "/F", "/PID", pid),
+// dir returns the directory that terraform is executed from
+func (j *job) dir() string {
+	if len(j.WorkDir) > 0 {
+		return j.WorkDir
 	}
-	if v, ok := killers[runtime.GOOS]; ok {
-		return v.Run()
-	}
-	return fmt.Errorf("runtime %s is not supported yet", runtime.GOOS)
+	return j.Path
 }
 
 // writeProvider writes the provider.tf file for each job
-func writeProvider(path string, services []string, port int) error {
-	data, err := getProvider(services, port)
+func writeProvider(path string, pc *ProviderConfig, services []string, endpoint, state string) error {
+	data, err := getProvider(pc, services, endpoint, state)
 	if err != nil {
 		return err
 	}
@@ -697,35 +1338,19 @@ var defaultServices = []string{
 }
 
 // getProvider returns the template formatted contents for the provider.tf
-func getProvider(services []string, port int) ([]byte, error) {
+func getProvider(pc *ProviderConfig, services []string, endpoint, state string) ([]byte, error) {
 	serviceList := defaultServices
 	if len(services) > 0 {
 		serviceList = services
 	}
 
-	tmpl := `terraform {
-	backend "local" {
-		path = "terraform.tfstate"
+	tmpl := providerTemplate
+	if pc != nil && len(pc.Template) > 0 {
+		tmpl = pc.Template
 	}
-}
-
-provider "aws" {
-	s3_force_path_style         = true
-	skip_credentials_validation = true
-	skip_metadata_api_check     = true
-	skip_requesting_account_id  = true
-	endpoints {
-{{- range .}}
-		{{.}} = "http://localhost:%d"
-{{- end}}
-	}
-}
-`
-	// add the port to the template data
-	tmpl = fmt.Sprintf(tmpl, port)
 
 	// create a new template and parse the data
-	t, err := template.New("provider").Parse(tmpl)
+	t, err := template.New("provider").Funcs(providerFuncs).Parse(tmpl)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template string: %v", err)
 	}
@@ -733,7 +1358,7 @@ provider "aws" {
 	// execute the template against a buffer so
 	// we can catch any errors
 	var byt bytes.Buffer
-	err = t.Execute(&byt, serviceList)
+	err = t.Execute(&byt, newProviderData(pc, serviceList, endpoint, state))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute template string: %v", err)
 	}
@@ -2,6 +2,7 @@ package tester
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -219,3 +220,46 @@ func TestGoTest(t *testing.T) {
 	}
 }
 `)
+
+// slowEmulator becomes ready after the emulator phase has timed out
+type slowEmulator struct {
+	External
+	stopped chan struct{}
+}
+
+func (s *slowEmulator) Ready() error {
+	time.Sleep(200 * time.Millisecond)
+	return nil
+}
+
+func (s *slowEmulator) Stop() error {
+	close(s.stopped)
+	return nil
+}
+
+func TestSetupEmulatorTimeout(t *testing.T) {
+	emu := &slowEmulator{External: External{URL: "http://localhost:4566"}, stopped: make(chan struct{})}
+	cfg := &Config{
+		Emulator: func() Emulator { return emu },
+		Timeouts: Timeouts{Emulator: 20 * time.Millisecond},
+	}
+	j := &job{Name: "slow", tail: &tailBuffer{}}
+	j.Stdout, j.Stderr = ioutil.Discard, ioutil.Discard
+
+	_, cleanup, err := j.setupEmulator(context.Background(), cfg)
+	if err == nil || cleanup != nil {
+		t.Fatalf("expected a timeout without a cleanup, got: %v", err)
+	}
+
+	// the emulator that is ready after the timeout must be stopped
+	select {
+	case <-emu.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("emulator was not stopped after the phase timed out")
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.Env) != 0 || j.emulator != nil {
+		t.Errorf("job modified after the phase timed out: %v", j.Env)
+	}
+}
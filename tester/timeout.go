@@ -0,0 +1,163 @@
+package tester
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timeouts contains the maximum duration of each job phase and of
+// the job as a whole, a zero value disables the timeout. When a
+// timeout fires, every process started during the phase is killed
+type Timeouts struct {
+	// Emulator is the time allowed for the emulator to become ready
+	Emulator time.Duration
+
+	// Fixtures is the time allowed for applying the
+	// dependencies and fixtures of the job
+	Fixtures time.Duration
+
+	// Init is the time allowed for terraform init
+	Init time.Duration
+
+	// Apply is the time allowed for terraform apply and output
+	Apply time.Duration
+
+	// Plan is the time allowed for terraform plan in plan-only
+	// mode and for the idempotency check
+	Plan time.Duration
+
+	// Test is the time allowed for go test
+	Test time.Duration
+
+	// Destroy is the time allowed for terraform destroy
+	// and the verification of leaked resources
+	Destroy time.Duration
+
+	// Hooks is the time allowed for the hooks of each hook point
+	Hooks time.Duration
+
+	// Job is the time allowed for all phases of the job combined
+	Job time.Duration
+}
+
+// the names of each job phase
+const (
+	phaseEmulator = "emulator"
+	phaseFixtures = "fixtures"
+	phaseInit     = "init"
+	phaseApply    = "apply"
+	phasePlan     = "plan"
+	phaseTest     = "test"
+	phaseDestroy  = "destroy"
+	phaseHooks    = "hooks"
+)
+
+// tailLines is the number of output lines kept for each job
+const tailLines = 20
+
+// withTimeout returns a context that is canceled after d,
+// or ctx itself with a no-op cancel func if d is zero
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// runPhase runs fn with a context limited to timeout, if the phase
+// or the job runs out of time the error reports the phase and the
+// last lines of output from the job
+func (j *job) runPhase(ctx context.Context, phase string, timeout time.Duration, fn func(context.Context) error) error {
+	j.setPhase(phase)
+	started := j.processCount()
+
+	pctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	// fn is run separately so phases that are not able
+	// to be interrupted still return once time runs out
+	result := make(chan error, 1)
+	go func() {
+		result <- fn(pctx)
+	}()
+
+	select {
+	case err := <-result:
+		if pctx.Err() == nil {
+			return err
+		}
+	case <-pctx.Done():
+	}
+
+	// kill anything the phase left running, the emulator is
+	// left to its own cleanup so its state is still able to be
+	// dumped and inspected by the failure hooks
+	j.killProcesses(started)
+
+	scope := "phase"
+	if ctx.Err() != nil {
+		scope = "job"
+	}
+	return fmt.Errorf("%s timed out during the %s phase, last output:\n%s", scope, phase, j.tail)
+}
+
+// watchContext kills the process tree once ctx is done
+func watchContext(ctx context.Context, p *Process) {
+	if ctx.Done() == nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		err := kill(p.Cmd)
+		if err != nil && !p.Exited() {
+			fmt.Printf("failed to kill process: %s -> %v\n", p.Path, err)
+		}
+	case <-p.done:
+	}
+}
+
+func (j *job) setPhase(phase string) {
+	j.mu.Lock()
+	j.Phase = phase
+	j.mu.Unlock()
+}
+
+// tailBuffer keeps the last lines written to it
+type tailBuffer struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (t *tailBuffer) add(line string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, line)
+	if len(t.lines) > tailLines {
+		t.lines = t.lines[len(t.lines)-tailLines:]
+	}
+}
+
+// reset removes every line
+func (t *tailBuffer) reset() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = nil
+}
+
+func (t *tailBuffer) String() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.lines, "\n")
+}
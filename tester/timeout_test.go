@@ -0,0 +1,122 @@
+package tester
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPhaseTimeout(t *testing.T) {
+	tt := map[string]struct {
+		phaseTimeout time.Duration
+		jobTimeout   time.Duration
+		expected     string
+	}{
+		"phase_timeout": {
+			phaseTimeout: 100 * time.Millisecond,
+			expected:     "phase timed out during the test phase",
+		},
+		"job_timeout": {
+			jobTimeout: 100 * time.Millisecond,
+			expected:   "job timed out during the test phase",
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			j := &job{Name: name, tail: &tailBuffer{}}
+			j.Stdout = &strings.Builder{}
+			j.Stderr = &strings.Builder{}
+
+			ctx, cancel := withTimeout(context.Background(), tc.jobTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := j.runPhase(ctx, phaseTest, tc.phaseTimeout, func(ctx context.Context) error {
+				p, err := j.startProcessContext(ctx, "go", "run", "-h")
+				if err != nil {
+					return err
+				}
+				<-ctx.Done()
+				return p.Wait()
+			})
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("error invalid, expected: %s, got: %v", tc.expected, err)
+			}
+			if time.Since(start) > 10*time.Second {
+				t.Errorf("phase was not interrupted in time")
+			}
+			if j.Phase != phaseTest {
+				t.Errorf("phase invalid, expected: %s, got: %s", phaseTest, j.Phase)
+			}
+		})
+	}
+}
+
+// helperEnv makes the test binary run TestHelperProcess
+const helperEnv = "TFTEST_HELPER_PROCESS=1"
+
+// TestHelperProcess blocks until it is killed when the
+// test binary is started as a process of a job
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("TFTEST_HELPER_PROCESS") != "1" {
+		return
+	}
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+func TestRunPhaseKillsPhaseProcesses(t *testing.T) {
+	j := &job{Name: "kill", tail: &tailBuffer{}, Env: []string{helperEnv}}
+	j.Stdout, j.Stderr = ioutil.Discard, ioutil.Discard
+
+	// the emulator is started before the phase and must survive it
+	emulator, err := j.startProcess(os.Args[0], "-test.run=TestHelperProcess")
+	if err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	defer kill(emulator.Cmd) //nolint: errcheck
+
+	err = j.runPhase(context.Background(), phaseApply, 100*time.Millisecond, func(ctx context.Context) error {
+		_, err := j.startProcess(os.Args[0], "-test.run=TestHelperProcess")
+		if err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "phase timed out during the apply phase") {
+		t.Fatalf("error invalid, expected a phase timeout, got: %v", err)
+	}
+
+	j.mu.Lock()
+	phase := j.Processes[1]
+	j.mu.Unlock()
+	select {
+	case <-phase.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("process of the phase was not killed")
+	}
+	if emulator.Exited() {
+		t.Error("process started before the phase was killed")
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tb := &tailBuffer{}
+	for i := 0; i < tailLines+5; i++ {
+		tb.add(strconv.Itoa(i))
+	}
+	lines := strings.Split(tb.String(), "\n")
+	if len(lines) != tailLines {
+		t.Fatalf("lines invalid, expected: %d, got: %d", tailLines, len(lines))
+	}
+	if lines[0] != "5" {
+		t.Errorf("first line invalid, expected: 5, got: %s", lines[0])
+	}
+}
@@ -0,0 +1,420 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultWatchInterval is how often job directories are polled for
+// changes when Config.WatchInterval is not set
+const defaultWatchInterval = time.Second
+
+// initInput matches the module and provider sources and versions that
+// terraform init installs, init is only run again when they change
+var initInput = regexp.MustCompile(`(?m)^\s*(?:source|version)\s*=.*$`)
+
+// watched returns true for the files that trigger another run of a job
+func watched(name string) bool {
+	if isGenerated(name) {
+		return false
+	}
+	return strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, "_test.go")
+}
+
+// fileStamps are the modification time and size of watched files by path
+type fileStamps map[string]string
+
+// stampFiles returns the stamps of the watched files directly inside of dirs
+func stampFiles(dirs []string) (fileStamps, error) {
+	stamps := make(fileStamps)
+	for _, dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files in: %q -> %v", dir, err)
+		}
+		for _, info := range infos {
+			if !info.Mode().IsRegular() || !watched(info.Name()) {
+				continue
+			}
+			stamps[filepath.Join(dir, info.Name())] = fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return stamps, nil
+}
+
+// initInputs returns every module and provider source and
+// version declared by the .tf files inside of dir
+func initInputs(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return "", fmt.Errorf("failed to list files in: %q -> %v", dir, err)
+	}
+	sort.Strings(matches)
+
+	var b strings.Builder
+	for _, m := range matches {
+		data, err := ioutil.ReadFile(m)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %q -> %v", m, err)
+		}
+		fmt.Fprintf(&b, "%s\n", filepath.Base(m))
+		for _, line := range initInput.FindAll(data, -1) {
+			fmt.Fprintf(&b, "%s\n", strings.TrimSpace(string(line)))
+		}
+	}
+	return b.String(), nil
+}
+
+// syncWorkspace copies the files of src into the workspace dst again,
+// watched files that have been removed from src are removed from dst
+func syncWorkspace(src, dst string) error {
+	infos, err := ioutil.ReadDir(dst)
+	if err != nil {
+		return fmt.Errorf("failed to list files in: %q -> %v", dst, err)
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if !info.Mode().IsRegular() || !watched(name) {
+			continue
+		}
+		_, err := os.Stat(filepath.Join(src, name))
+		if ignoreNotExistsErr(err) != nil {
+			return fmt.Errorf("failed to stat file: %q -> %v", name, err)
+		}
+		if err == nil {
+			continue
+		}
+		err = os.Remove(filepath.Join(dst, name))
+		if err != nil {
+			return fmt.Errorf("failed to remove file: %q -> %v", name, err)
+		}
+	}
+	return copyWorkspace(src, dst)
+}
+
+// watchDirs returns the directories that the job is applied from
+func (j *job) watchDirs() []string {
+	dirs := []string{j.Path}
+	for _, f := range j.Fixtures {
+		dirs = append(dirs, f.Path)
+	}
+	return dirs
+}
+
+// watcher runs every job once and keeps its emulator and initialized
+// workspace, each job is run again when its watched files change
+type watcher struct {
+	cfg      *Config
+	out      io.Writer
+	interval time.Duration
+
+	// stops contains the func that stops the
+	// kept emulator of each job by job
+	mu    sync.Mutex
+	stops map[*job]func()
+	stop  chan struct{}
+}
+
+func newWatcher(cfg *Config, out io.Writer) *watcher {
+	interval := cfg.WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	return &watcher{
+		cfg:      cfg,
+		out:      out,
+		interval: interval,
+		stops:    make(map[*job]func()),
+		stop:     make(chan struct{}),
+	}
+}
+
+// start watches every job that was not skipped, the jobs share
+// the job slots while they run but not while they are waiting
+func (w *watcher) start(jobs []*job) {
+	size := capacity(w.cfg)
+	free := newSlots(size)
+	for _, j := range jobs {
+		if j.Skipped {
+			continue
+		}
+		go w.watch(j, free, size)
+	}
+}
+
+// close stops watching and stops the emulator of every job
+func (w *watcher) close() {
+	close(w.stop)
+
+	w.mu.Lock()
+	stops := w.stops
+	w.stops = nil
+	w.mu.Unlock()
+	for _, stop := range stops {
+		stop()
+	}
+}
+
+// setStop keeps the func that stops the emulator of the job, the
+// emulator is stopped right away if the watcher has been closed
+func (w *watcher) setStop(j *job, stop func()) {
+	w.mu.Lock()
+	closed := w.stops == nil
+	if !closed {
+		w.stops[j] = stop
+	}
+	w.mu.Unlock()
+	if closed {
+		stop()
+	}
+}
+
+// stopEmulator stops the kept emulator of the job
+func (w *watcher) stopEmulator(j *job) {
+	w.mu.Lock()
+	stop, ok := w.stops[j]
+	delete(w.stops, j)
+	w.mu.Unlock()
+	if ok {
+		stop()
+	}
+}
+
+// watch runs the job each time its watched files change until the
+// watcher is closed
+func (w *watcher) watch(j *job, free *slots, size int) {
+	j.setLogs(w.cfg)
+	j.setCoverage(w.cfg)
+	j.setLedger(w.cfg)
+	j.tool = w.cfg.Driver
+
+	stamps, err := stampFiles(j.watchDirs())
+	if err != nil {
+		j.printf("failed to watch files: %v\n", err)
+	}
+
+	r := &rerun{args: append([]string{}, j.VarArgs...)}
+	weight := j.weight(size)
+	for {
+		free.acquire(weight)
+		j.begin()
+		j.finish(w.run(j, r))
+		free.release(weight)
+		w.report(j)
+
+		var ok bool
+		stamps, ok = w.changed(j, stamps)
+		if !ok {
+			return
+		}
+	}
+}
+
+// rerun is the state of a watched job that is kept between runs
+type rerun struct {
+	endpoint string
+	inputs   string
+	base     []string
+	env      []string
+	args     []string
+
+	// processes are the processes of the kept emulator
+	processes []*Process
+}
+
+// exited returns true if any process of the kept emulator has exited
+func (r *rerun) exited() bool {
+	for _, p := range r.processes {
+		if p.Exited() {
+			return true
+		}
+	}
+	return false
+}
+
+// run applies and tests the job, the workspace and emulator are
+// prepared by the first run and reused by every run afterwards
+func (w *watcher) run(j *job, r *rerun) (err error) {
+	cfg := w.cfg
+	ctx, cancel := withTimeout(context.Background(), cfg.Timeouts.Job)
+	defer cancel()
+
+	defer func() {
+		if err != nil {
+			j.dumpState(cfg, r.endpoint)
+			err = j.runFailureHooks(cfg, err)
+		}
+	}()
+
+	if len(j.WorkDir) == 0 {
+		err = j.prepareWorkspace(cfg.WorkspaceDir)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = j.refresh(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	if j.planOnly(cfg) {
+		return j.runPlanOnly(ctx, cfg)
+	}
+
+	if j.cassetteMode(cfg) == CassetteReplay {
+		return j.runReplay(ctx, cfg)
+	}
+
+	// the emulator is started again when it has crashed or
+	// has been killed, since resetting it would always fail
+	if len(r.endpoint) > 0 && r.exited() {
+		j.printf("emulator exited, starting a new emulator...\n")
+		w.stopEmulator(j)
+		r.endpoint = ""
+		j.mu.Lock()
+		j.Env = append([]string{}, r.base...)
+		j.mu.Unlock()
+	}
+
+	init := false
+	if len(r.endpoint) == 0 {
+		r.base = append([]string{}, j.Env...)
+		started := j.processCount()
+		endpoint, cleanup, err := j.setupEmulator(ctx, cfg)
+		if err != nil {
+			if cleanup != nil {
+				j.dumpState(cfg, endpoint)
+				cleanup()
+			}
+			return err
+		}
+		w.setStop(j, cleanup)
+		r.endpoint = endpoint
+		r.env = append([]string{}, j.Env...)
+		r.processes = j.runningProcesses(started)
+		init = true
+	} else {
+		j.printf("resetting emulator...\n")
+		resetter, ok := j.emulator.(Resetter)
+		if !ok {
+			return errors.New("watch mode requires an emulator implementing Resetter")
+		}
+		err = resetter.Reset()
+		if err != nil {
+			return err
+		}
+	}
+
+	inputs, err := initInputs(j.dir())
+	if err != nil {
+		return err
+	}
+	if inputs != r.inputs {
+		init = true
+	}
+	err = j.runSteps(ctx, cfg, r.endpoint, init)
+	if init && j.initialized {
+		r.inputs = inputs
+	}
+	return err
+}
+
+// refresh prepares the workspace of the job for another run, the
+// state is removed since the resources it contains have been reset
+func (j *job) refresh(r *rerun) error {
+	j.tail.reset()
+
+	j.mu.Lock()
+	if r.env != nil {
+		j.Env = append([]string{}, r.env...)
+	}
+	j.VarArgs = append([]string{}, r.args...)
+	// only the processes of the emulator are still running
+	var running []*Process
+	for _, p := range j.Processes {
+		if !p.Exited() {
+			running = append(running, p)
+		}
+	}
+	j.Processes = running
+	j.mu.Unlock()
+
+	err := syncWorkspace(j.Path, j.WorkDir)
+	if err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(j.WorkDir, "fixture-*"))
+	if err != nil {
+		return fmt.Errorf("failed to list fixtures in: %q -> %v", j.WorkDir, err)
+	}
+	paths = append(paths, j.StateFile, j.StateFile+".backup", j.OutputsFile, j.PlanFile)
+	for _, path := range paths {
+		err = ignoreNotExistsErr(os.RemoveAll(path))
+		if err != nil {
+			return fmt.Errorf("failed to remove: %q -> %v", path, err)
+		}
+	}
+	return nil
+}
+
+// report prints the result of the latest run of the job
+func (w *watcher) report(j *job) {
+	var b strings.Builder
+	s := j.state(time.Now())
+	if j.Err != nil {
+		fmt.Fprintf(&b, "%s %v\n", s, j.Err)
+		reportFailure(&b, j)
+	} else {
+		fmt.Fprintln(&b, s)
+		reportResources(&b, j)
+	}
+	fmt.Fprintf(&b, "%-20swatching for changes...\n", j.Name)
+
+	_, err := io.WriteString(w.out, b.String())
+	if err != nil {
+		fmt.Printf("failed to write job results: %v\n", err)
+	}
+}
+
+// changed blocks until the watched files of the job differ from stamps
+// and have stopped changing for an interval, so a burst of writes from
+// an editor results in a single run, it returns false once the watcher
+// is closed
+func (w *watcher) changed(j *job, stamps fileStamps) (fileStamps, bool) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	pending := false
+	for {
+		select {
+		case <-w.stop:
+			return nil, false
+		case <-ticker.C:
+		}
+
+		current, err := stampFiles(j.watchDirs())
+		if err != nil {
+			j.printf("failed to watch files: %v\n", err)
+			continue
+		}
+		switch {
+		case !reflect.DeepEqual(current, stamps):
+			stamps, pending = current, true
+		case pending:
+			return stamps, true
+		}
+	}
+}
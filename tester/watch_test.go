@@ -0,0 +1,206 @@
+package tester
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatched(t *testing.T) {
+	tt := map[string]bool{
+		"main.tf":           true,
+		"main_test.go":      true,
+		"helpers.go":        false,
+		"vars.tfvars":       false,
+		"provider.tf":       false,
+		"terraform.tfstate": false,
+	}
+
+	for name, expected := range tt {
+		name, expected := name, expected
+		t.Run(name, func(t *testing.T) {
+			actual := watched(name)
+			if actual != expected {
+				t.Errorf("watched invalid, expected: %t, got: %t", expected, actual)
+			}
+		})
+	}
+}
+
+func TestInitInputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inputs")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(data string) string {
+		err := ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte(data), 0600)
+		if err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		inputs, err := initInputs(dir)
+		if err != nil {
+			t.Fatalf("failed to read init inputs: %v", err)
+		}
+		return inputs
+	}
+
+	before := write("module \"a\" {\n  source = \"./a\"\n  name = \"one\"\n}\n")
+	renamed := write("module \"a\" {\n  source = \"./a\"\n  name = \"two\"\n}\n")
+	if before != renamed {
+		t.Errorf("init inputs changed by an argument: %q != %q", before, renamed)
+	}
+	moved := write("module \"a\" {\n  source = \"./b\"\n  name = \"two\"\n}\n")
+	if before == moved {
+		t.Errorf("init inputs not changed by a source: %q", moved)
+	}
+}
+
+func TestSyncWorkspace(t *testing.T) {
+	src, err := ioutil.TempDir("", "src")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(src)
+	dst, err := ioutil.TempDir("", "dst")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dst)
+
+	files := map[string]string{
+		filepath.Join(src, "main.tf"):             "updated",
+		filepath.Join(dst, "main.tf"):             "original",
+		filepath.Join(dst, "removed.tf"):          "removed",
+		filepath.Join(dst, "provider.tf"):         "provider",
+		filepath.Join(dst, ".terraform.lock.hcl"): "lock",
+	}
+	for path, data := range files {
+		err = ioutil.WriteFile(path, []byte(data), 0600)
+		if err != nil {
+			t.Fatalf("failed to write file: %s -> %v", path, err)
+		}
+	}
+
+	err = syncWorkspace(src, dst)
+	if err != nil {
+		t.Fatalf("failed to sync workspace: %v", err)
+	}
+
+	expected := map[string]string{
+		"main.tf":             "updated",
+		"removed.tf":          "",
+		"provider.tf":         "provider",
+		".terraform.lock.hcl": "lock",
+	}
+	for name, data := range expected {
+		actual, err := ioutil.ReadFile(filepath.Join(dst, name))
+		if len(data) == 0 {
+			if !os.IsNotExist(err) {
+				t.Errorf("file not removed: %s", name)
+			}
+			continue
+		}
+		if string(actual) != data {
+			t.Errorf("file invalid: %s, expected: %s, got: %s", name, data, actual)
+		}
+	}
+}
+
+func TestWatcherChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "main.tf")
+	err = ioutil.WriteFile(path, []byte("original"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	j := &job{Name: "watch", Path: dir}
+	stamps, err := stampFiles(j.watchDirs())
+	if err != nil {
+		t.Fatalf("failed to stamp files: %v", err)
+	}
+
+	w := newWatcher(&Config{WatchInterval: 10 * time.Millisecond}, ioutil.Discard)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		err := ioutil.WriteFile(path, []byte("changed"), 0600)
+		if err != nil {
+			t.Errorf("failed to write file: %v", err)
+		}
+	}()
+
+	current, ok := w.changed(j, stamps)
+	if !ok {
+		t.Fatalf("changed invalid, expected: true, got: false")
+	}
+	if current[path] == stamps[path] {
+		t.Errorf("stamps not updated: %s", current[path])
+	}
+
+	w.close()
+	_, ok = w.changed(j, current)
+	if ok {
+		t.Errorf("changed invalid after close, expected: false, got: true")
+	}
+}
+
+func TestRerunExited(t *testing.T) {
+	running := &Process{done: make(chan struct{})}
+	exited := &Process{done: make(chan struct{})}
+	close(exited.done)
+
+	tt := map[string]struct {
+		processes []*Process
+		expected  bool
+	}{
+		"external": {},
+		"running":  {processes: []*Process{running}},
+		"exited":   {processes: []*Process{running, exited}, expected: true},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r := &rerun{processes: tc.processes}
+			if actual := r.exited(); actual != tc.expected {
+				t.Errorf("exited invalid, expected: %t, got: %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWatcherStops(t *testing.T) {
+	w := newWatcher(&Config{}, ioutil.Discard)
+	a, b := &job{Name: "a"}, &job{Name: "b"}
+
+	stopped := make(map[string]int)
+	w.setStop(a, func() { stopped["a"]++ })
+	w.setStop(b, func() { stopped["b"]++ })
+
+	// the dead emulator of a is stopped once and forgotten
+	w.stopEmulator(a)
+	w.stopEmulator(a)
+	if stopped["a"] != 1 || stopped["b"] != 0 {
+		t.Fatalf("stops invalid after stopping a: %v", stopped)
+	}
+
+	w.close()
+	if stopped["a"] != 1 || stopped["b"] != 1 {
+		t.Fatalf("stops invalid after close: %v", stopped)
+	}
+
+	// an emulator kept after close is stopped right away
+	w.setStop(a, func() { stopped["a"]++ })
+	if stopped["a"] != 2 {
+		t.Errorf("emulator kept after close was not stopped: %v", stopped)
+	}
+}
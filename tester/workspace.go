@@ -0,0 +1,138 @@
+package tester
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// unsafeChars matches characters that are replaced in file names
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// moduleSource matches local module sources in Terraform files
+var moduleSource = regexp.MustCompile(`(\bsource\s*=\s*")(\.\.?/[^"]*)(")`)
+
+// prepareWorkspace copies the files of the job directory into a new
+// temporary directory under base that terraform is executed from, the
+// job directory itself is never written to
+func (j *job) prepareWorkspace(base string) error {
+	prefix := "tftest-" + unsafeChars.ReplaceAllString(j.Name, "_") + "-"
+	dir, err := ioutil.TempDir(base, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %v", err)
+	}
+	j.WorkDir = dir
+	j.ProviderFile = filepath.Join(dir, "provider.tf")
+	j.StateFile = filepath.Join(dir, "terraform.tfstate")
+	j.OutputsFile = filepath.Join(dir, "terraform.outputs.json")
+	j.PlanFile = filepath.Join(dir, "terraform.plan.json")
+
+	return copyWorkspace(j.Path, dir)
+}
+
+// copyWorkspace copies the files and subdirectories of src into dst,
+// leaving out anything generated by a previous run, local module
+// sources in .tf and .hcl files are rewritten relative to dst
+func copyWorkspace(src, dst string) error {
+	infos, err := ioutil.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("failed to list files in: %q -> %v", src, err)
+	}
+
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() {
+			if isGeneratedDir(name) {
+				continue
+			}
+			// files such as policies and templates are
+			// read from subdirectories using path.module
+			err = os.MkdirAll(filepath.Join(dst, name), info.Mode().Perm()|0700)
+			if err != nil {
+				return fmt.Errorf("failed to create directory: %q -> %v", name, err)
+			}
+			err = copyWorkspace(filepath.Join(src, name), filepath.Join(dst, name))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !info.Mode().IsRegular() || isGenerated(name) {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			return fmt.Errorf("failed to read file: %q -> %v", name, err)
+		}
+
+		// terragrunt.hcl uses the same source attribute as modules
+		if strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".hcl") {
+			data = rewriteSources(data, src, dst)
+		}
+
+		err = ioutil.WriteFile(filepath.Join(dst, name), data, info.Mode().Perm())
+		if err != nil {
+			return fmt.Errorf("failed to copy file: %q -> %v", name, err)
+		}
+	}
+	return nil
+}
+
+// isGenerated returns true for files written by tester or terraform
+func isGenerated(name string) bool {
+	return name == "provider.tf" ||
+		strings.Contains(name, ".tfstate") ||
+		strings.HasSuffix(name, ".outputs.json") ||
+		strings.HasSuffix(name, ".plan.json") ||
+		strings.HasSuffix(name, ".tfplan")
+}
+
+// isGeneratedDir returns true for directories written by terraform
+// or terragrunt, which are never copied into a workspace
+func isGeneratedDir(name string) bool {
+	return name == ".terraform" || name == ".terragrunt-cache"
+}
+
+// rewriteSources rewrites local module sources relative to src so
+// they resolve to the same directories from dst
+func rewriteSources(data []byte, src, dst string) []byte {
+	return moduleSource.ReplaceAllFunc(data, func(m []byte) []byte {
+		parts := moduleSource.FindSubmatch(m)
+		target := filepath.Join(src, string(parts[2]))
+
+		// keep the path relative so terraform still
+		// treats it as a local module source
+		rel, err := filepath.Rel(dst, target)
+		if err != nil {
+			rel = target
+		} else if !strings.HasPrefix(rel, "..") {
+			rel = "./" + rel
+		}
+		return []byte(string(parts[1]) + filepath.ToSlash(rel) + string(parts[3]))
+	})
+}
+
+// removeWorkspace deletes the workspace unless the job failed and
+// keep is set, in which case it is preserved for debugging
+func (j *job) removeWorkspace(keep bool) {
+	if len(j.WorkDir) == 0 {
+		return
+	}
+
+	if keep && j.Err != nil && !j.Skipped {
+		j.Artifacts = j.WorkDir
+		return
+	}
+
+	err := retrier(100*time.Millisecond, 10, func() error {
+		return ignoreNotExistsErr(os.RemoveAll(j.WorkDir))
+	})
+	if err != nil {
+		j.printf("failed to cleanup directory: %s -> %v\n", j.WorkDir, err)
+	}
+}
@@ -0,0 +1,105 @@
+package tester
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewriteSources(t *testing.T) {
+	tt := map[string]struct {
+		in       string
+		expected string
+	}{
+		"parent": {
+			in:       `source = "../modules/bucket"`,
+			expected: `source = "../../src/modules/bucket"`,
+		},
+		"current": {
+			in:       `source  =  "./child"`,
+			expected: `source  =  "../../src/job/child"`,
+		},
+		"registry": {
+			in:       `source = "terraform-aws-modules/vpc/aws"`,
+			expected: `source = "terraform-aws-modules/vpc/aws"`,
+		},
+	}
+
+	src := filepath.FromSlash("/tmp/src/job")
+	dst := filepath.FromSlash("/tmp/ws/job")
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual := string(rewriteSources([]byte(tc.in), src, dst))
+			if actual != tc.expected {
+				t.Errorf("source invalid, expected: %s, got: %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPrepareWorkspace(t *testing.T) {
+	src, err := ioutil.TempDir("", "src")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(src)
+
+	files := map[string]bool{
+		"main.tf":           true,
+		"main_test.go":      true,
+		"vars.tfvars":       true,
+		"provider.tf":       false,
+		"terraform.tfstate": false,
+	}
+	for f := range files {
+		err = ioutil.WriteFile(filepath.Join(src, f), []byte(f), 0600)
+		if err != nil {
+			t.Fatalf("failed to write file: %s -> %v", f, err)
+		}
+	}
+	err = os.Mkdir(filepath.Join(src, ".terraform"), 0700)
+	if err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	err = os.MkdirAll(filepath.Join(src, "policies", "s3"), 0700)
+	if err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	policy := filepath.Join("policies", "s3", "bucket.json")
+	err = ioutil.WriteFile(filepath.Join(src, policy), []byte("{}"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write file: %s -> %v", policy, err)
+	}
+
+	for name, keep := range map[string]bool{"removed": false, "kept": true} {
+		j := newJob(name, src, src, "main_test.go", nil, &JobConfig{})
+		err = j.prepareWorkspace("")
+		if err != nil {
+			t.Fatalf("failed to prepare workspace: %v", err)
+		}
+		for f, copied := range files {
+			_, err := os.Stat(filepath.Join(j.WorkDir, f))
+			if copied != (err == nil) {
+				t.Errorf("file %s copied: %t, expected: %t", f, err == nil, copied)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(j.WorkDir, ".terraform")); err == nil {
+			t.Errorf(".terraform must not be copied into the workspace")
+		}
+		if data, err := ioutil.ReadFile(filepath.Join(j.WorkDir, policy)); err != nil || string(data) != "{}" {
+			t.Errorf("subdirectory not copied into the workspace: %s -> %v", policy, err)
+		}
+
+		j.removeWorkspace(keep)
+		_, err = os.Stat(j.WorkDir)
+		if keep != (err == nil) {
+			t.Errorf("workspace kept: %t, expected: %t", err == nil, keep)
+		}
+		if keep && j.Artifacts != j.WorkDir {
+			t.Errorf("artifacts invalid, expected: %s, got: %s", j.WorkDir, j.Artifacts)
+		}
+		os.RemoveAll(j.WorkDir)
+	}
+}
@@ -0,0 +1,184 @@
+package tfplan
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/GSA/grace-tftest/aws/shared"
+)
+
+type check func(r *Planned, c *ResourceChange) error
+
+// Matcher contains the properties for testing a planned resource
+type Matcher struct {
+	plan    *Plan
+	address string
+	checks  []check
+}
+
+// Resource returns a new *Matcher for the address, for example:
+// aws_s3_bucket.logs or module.trail.aws_cloudtrail.trail, the plan
+// is read from TF_PLAN_FILE when Assert is called
+func Resource(address string) *Matcher {
+	return &Matcher{address: address}
+}
+
+// Resource returns a new *Matcher for the address using the plan
+func (p *Plan) Resource(address string) *Matcher {
+	return &Matcher{plan: p, address: address}
+}
+
+// Attr adds the Attr check to the check list
+// the Attr check: verifies the planned value at the dotted 'path',
+// for example: versioning.0.enabled, is equal to 'expected'
+func (r *Matcher) Attr(path string, expected interface{}) *Matcher {
+	r.checks = append(r.checks, func(p *Planned, c *ResourceChange) error {
+		actual, ok := lookup(p.Values, path)
+		if !ok {
+			return fmt.Errorf("%s: attribute %s is not set", r.address, path)
+		}
+		equal, err := equalValues(expected, actual)
+		if err != nil {
+			return fmt.Errorf("%s: failed to compare attribute %s -> %v", r.address, path, err)
+		}
+		shared.Debugf("%v == %v -> %t\n", expected, actual, equal)
+		if !equal {
+			return fmt.Errorf("%s: attribute %s invalid, expected: %v, got: %v", r.address, path, expected, actual)
+		}
+		return nil
+	})
+	return r
+}
+
+// Unknown adds the Unknown check to the check list
+// the Unknown check: verifies the value at the dotted 'path'
+// is not known until the plan is applied, for example: arn
+func (r *Matcher) Unknown(path string) *Matcher {
+	r.checks = append(r.checks, func(p *Planned, c *ResourceChange) error {
+		if c == nil {
+			return fmt.Errorf("%s: resource is not changed by the plan", r.address)
+		}
+		unknown, ok := lookup(c.Change.AfterUnknown, path)
+		if !ok || unknown != true {
+			return fmt.Errorf("%s: attribute %s is known before apply", r.address, path)
+		}
+		return nil
+	})
+	return r
+}
+
+// Action adds the Action check to the check list
+// the Action check: verifies the actions planned for
+// the resource, for example: create or delete, create
+func (r *Matcher) Action(actions ...string) *Matcher {
+	r.checks = append(r.checks, func(p *Planned, c *ResourceChange) error {
+		actual := []string{"no-op"}
+		if c != nil {
+			actual = c.Change.Actions
+		}
+		if strings.Join(actions, ", ") != strings.Join(actual, ", ") {
+			return fmt.Errorf("%s: actions invalid, expected: %v, got: %v", r.address, actions, actual)
+		}
+		return nil
+	})
+	return r
+}
+
+// Values returns the planned values of the resource
+func (r *Matcher) Values(t *testing.T) map[string]interface{} {
+	p, err := r.planned()
+	if err != nil {
+		t.Fatal(err)
+		return nil
+	}
+	return p.Values
+}
+
+// Assert fails the test if the resource is not part of the plan or
+// any of the checks that have been called fail, then resets the checks
+func (r *Matcher) Assert(t *testing.T) *Matcher {
+	for _, err := range r.verify() {
+		t.Error(err)
+	}
+	r.checks = nil
+	return r
+}
+
+// verify runs every check and returns their errors
+func (r *Matcher) verify() []error {
+	p, err := r.planned()
+	if err != nil {
+		return []error{err}
+	}
+	c := r.plan.Change(r.address)
+
+	var errs []error
+	for _, check := range r.checks {
+		err = check(p, c)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// planned returns the planned resource, reading the plan if necessary
+func (r *Matcher) planned() (*Planned, error) {
+	if r.plan == nil {
+		p, err := Read()
+		if err != nil {
+			return nil, err
+		}
+		r.plan = p
+	}
+	p := r.plan.Planned(r.address)
+	if p == nil {
+		return nil, fmt.Errorf("%s: resource is not part of the plan", r.address)
+	}
+	return p, nil
+}
+
+// lookup returns the value at the dotted path inside of v
+func lookup(v interface{}, path string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			v, ok = t[key]
+			if !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+			v = t[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// equalValues compares expected to a value decoded from JSON by
+// encoding and decoding expected first, so 1 is equal to 1.0
+func equalValues(expected, actual interface{}) (bool, error) {
+	data, err := json.Marshal(expected)
+	if err != nil {
+		return false, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(normalized, actual), nil
+}
@@ -0,0 +1,66 @@
+package tfplan
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	p := &Plan{}
+	err := json.Unmarshal(testPlan, p)
+	if err != nil {
+		t.Fatalf("failed to parse plan: %v", err)
+	}
+
+	tt := map[string]struct {
+		m        *Matcher
+		expected []string
+	}{
+		"attrs": {
+			m: p.Resource("aws_s3_bucket.logs").
+				Attr("versioning.0.enabled", true).
+				Attr("lifecycle_rule.0.expiration.0.days", 90).
+				Attr("tags", map[string]string{"Name": "logs"}),
+		},
+		"unknown_and_action": {
+			m: p.Resource("aws_s3_bucket.logs").Unknown("arn").Action("create"),
+		},
+		"child_module": {
+			m: p.Resource("module.trail.aws_cloudtrail.trail").Attr("s3_bucket_name", "logs").Action("no-op"),
+		},
+		"invalid_attr": {
+			m: p.Resource("aws_s3_bucket.logs").Attr("force_destroy", true).Attr("versioning.1.enabled", true),
+			expected: []string{
+				"attribute force_destroy invalid, expected: true, got: false",
+				"attribute versioning.1.enabled is not set",
+			},
+		},
+		"invalid_unknown_and_action": {
+			m: p.Resource("aws_s3_bucket.logs").Unknown("bucket").Action("delete", "create"),
+			expected: []string{
+				"attribute bucket is known before apply",
+				"actions invalid, expected: [delete create], got: [create]",
+			},
+		},
+		"missing": {
+			m:        p.Resource("aws_s3_bucket.missing"),
+			expected: []string{"aws_s3_bucket.missing: resource is not part of the plan"},
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			errs := tc.m.verify()
+			if len(errs) != len(tc.expected) {
+				t.Fatalf("errors invalid, expected: %v, got: %v", tc.expected, errs)
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), tc.expected[i]) {
+					t.Errorf("error invalid, expected: %s, got: %v", tc.expected[i], err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package tfplan provides matching operations against the JSON
+// representation of a Terraform plan, so modules are able to be
+// tested without applying them
+package tfplan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// FileEnv is the ENV variable containing the path to the JSON
+// file holding the `terraform show -json` of the job's plan
+const FileEnv = "TF_PLAN_FILE"
+
+// Plan is the JSON representation of a Terraform plan
+type Plan struct {
+	FormatVersion    string                 `json:"format_version"`
+	TerraformVersion string                 `json:"terraform_version"`
+	Variables        map[string]Variable    `json:"variables"`
+	PlannedValues    Values                 `json:"planned_values"`
+	ResourceChanges  []ResourceChange       `json:"resource_changes"`
+	OutputChanges    map[string]Change      `json:"output_changes"`
+	Configuration    map[string]interface{} `json:"configuration"`
+	PriorState       map[string]interface{} `json:"prior_state"`
+}
+
+// Variable is the value of an input variable of the plan
+type Variable struct {
+	Value interface{} `json:"value"`
+}
+
+// Values are the planned values of the outputs and resources
+type Values struct {
+	Outputs    map[string]PlannedOutput `json:"outputs"`
+	RootModule Module                   `json:"root_module"`
+}
+
+// PlannedOutput is the planned value of an output
+type PlannedOutput struct {
+	Sensitive bool        `json:"sensitive"`
+	Value     interface{} `json:"value"`
+}
+
+// Module contains the planned resources of a module and its children
+type Module struct {
+	Address      string    `json:"address"`
+	Resources    []Planned `json:"resources"`
+	ChildModules []Module  `json:"child_modules"`
+}
+
+// Planned is the planned state of a single resource
+type Planned struct {
+	Address      string                 `json:"address"`
+	Mode         string                 `json:"mode"`
+	Type         string                 `json:"type"`
+	Name         string                 `json:"name"`
+	Index        interface{}            `json:"index"`
+	ProviderName string                 `json:"provider_name"`
+	Values       map[string]interface{} `json:"values"`
+}
+
+// ResourceChange is the change planned for a single resource
+type ResourceChange struct {
+	Address       string `json:"address"`
+	ModuleAddress string `json:"module_address"`
+	Mode          string `json:"mode"`
+	Type          string `json:"type"`
+	Name          string `json:"name"`
+	Change        Change `json:"change"`
+}
+
+// Change contains the actions and values of a planned change
+type Change struct {
+	Actions      []string    `json:"actions"`
+	Before       interface{} `json:"before"`
+	After        interface{} `json:"after"`
+	AfterUnknown interface{} `json:"after_unknown"`
+}
+
+// Read returns the plan from the file referenced by TF_PLAN_FILE
+func Read() (*Plan, error) {
+	path := os.Getenv(FileEnv)
+	if len(path) == 0 {
+		return nil, errors.New(FileEnv + " is not set, the job must be run in plan-only mode")
+	}
+	return ReadFile(path)
+}
+
+// ReadFile returns the plan from the `terraform show -json` file at path
+func ReadFile(path string) (*Plan, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %q -> %v", path, err)
+	}
+	p := &Plan{}
+	err = json.Unmarshal(data, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plan file: %q -> %v", path, err)
+	}
+	return p, nil
+}
+
+// Load calls Read and fails the test if the plan cannot be read
+func Load(t *testing.T) *Plan {
+	p, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// Planned returns the planned resource with the address
+// or nil if the plan does not contain the resource
+func (p *Plan) Planned(address string) *Planned {
+	return p.PlannedValues.RootModule.find(address)
+}
+
+// Change returns the change planned for the resource with
+// the address or nil if the plan does not change the resource
+func (p *Plan) Change(address string) *ResourceChange {
+	for i := range p.ResourceChanges {
+		if p.ResourceChanges[i].Address == address {
+			return &p.ResourceChanges[i]
+		}
+	}
+	return nil
+}
+
+func (m *Module) find(address string) *Planned {
+	for i := range m.Resources {
+		if m.Resources[i].Address == address {
+			return &m.Resources[i]
+		}
+	}
+	for i := range m.ChildModules {
+		if r := m.ChildModules[i].find(address); r != nil {
+			return r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package tfplan
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testPlan = []byte(`{
+  "format_version": "0.2",
+  "terraform_version": "1.0.0",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_s3_bucket.logs",
+          "mode": "managed",
+          "type": "aws_s3_bucket",
+          "name": "logs",
+          "values": {
+            "bucket": "logs",
+            "force_destroy": false,
+            "tags": {"Name": "logs"},
+            "versioning": [{"enabled": true, "mfa_delete": false}],
+            "lifecycle_rule": [{"expiration": [{"days": 90}]}]
+          }
+        }
+      ],
+      "child_modules": [
+        {
+          "address": "module.trail",
+          "resources": [
+            {
+              "address": "module.trail.aws_cloudtrail.trail",
+              "mode": "managed",
+              "type": "aws_cloudtrail",
+              "name": "trail",
+              "values": {"name": "trail", "s3_bucket_name": "logs"}
+            }
+          ]
+        }
+      ]
+    }
+  },
+  "resource_changes": [
+    {
+      "address": "aws_s3_bucket.logs",
+      "mode": "managed",
+      "type": "aws_s3_bucket",
+      "name": "logs",
+      "change": {
+        "actions": ["create"],
+        "before": null,
+        "after": {"bucket": "logs"},
+        "after_unknown": {"arn": true, "id": true}
+      }
+    }
+  ]
+}`)
+
+func writePlan(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "tfplan")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	path := filepath.Join(dir, "plan.json")
+	err = ioutil.WriteFile(path, testPlan, 0600)
+	if err != nil {
+		t.Fatalf("failed to write plan: %v", err)
+	}
+	return path
+}
+
+func TestRead(t *testing.T) {
+	path := writePlan(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	err := os.Setenv(FileEnv, path)
+	if err != nil {
+		t.Fatalf("failed to set env: %v", err)
+	}
+	defer os.Unsetenv(FileEnv)
+
+	p := Load(t)
+	if p.TerraformVersion != "1.0.0" {
+		t.Errorf("terraform version invalid, expected: 1.0.0, got: %s", p.TerraformVersion)
+	}
+	if p.Planned("module.trail.aws_cloudtrail.trail") == nil {
+		t.Errorf("failed to find resource inside of child module")
+	}
+	if p.Change("aws_s3_bucket.logs") == nil {
+		t.Errorf("failed to find resource change")
+	}
+
+	// the package level builder reads TF_PLAN_FILE
+	Resource("aws_s3_bucket.logs").Attr("versioning.0.enabled", true).Assert(t)
+}
+
+func TestReadMissingEnv(t *testing.T) {
+	os.Unsetenv(FileEnv)
+	_, err := Read()
+	if err == nil || !strings.Contains(err.Error(), FileEnv) {
+		t.Errorf("error invalid, got: %v", err)
+	}
+}